@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// parseDialectName maps a --dialect/schedule-config dialect name onto its
+// Dialect implementation.
+func parseDialectName(name string) (Dialect, error) {
+	switch name {
+	case "postgres":
+		return PostgresDialect{}, nil
+	case "mysql":
+		return MySQLDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported dialect %q (want postgres or mysql)", name)
+	}
+}
+
+// Dialect abstracts the SQL-text formatting rules a dump target requires:
+// how to write bytes, timestamps, arrays, and JSON as literals, how to
+// quote an identifier, how a source driver type name classifies for that
+// formatting, and how a Postgres information_schema type name maps onto
+// the target's own type names. DBDumpService dumps from Postgres, but
+// adding a dump target beyond Postgres is a matter of implementing this
+// interface rather than touching the dump pipeline itself.
+type Dialect interface {
+	// QuoteIdent quotes name as an identifier, doubling any quote
+	// characters already present.
+	QuoteIdent(name string) string
+	// IsReserved reports whether word is a reserved keyword in this
+	// dialect, i.e. whether it requires quoting even when it would
+	// otherwise be a valid bare identifier.
+	IsReserved(word string) bool
+	// ColumnTypeKind classifies a driver-reported database type name
+	// (e.g. "BYTEA", "TIMESTAMPTZ", "_INT4", "JSONB") into a formatting
+	// kind: "bytes", "time", "json", "array", or "" for everything
+	// formatValue already handles directly (numbers, bools, plain text,
+	// NUMERIC, UUID, INET).
+	ColumnTypeKind(dbType string) string
+	// FormatBytes renders raw bytes as a quoted dialect literal.
+	FormatBytes(b []byte) string
+	// FormatTime renders t, converted into loc, as a quoted dialect
+	// literal.
+	FormatTime(t time.Time, loc *time.Location) string
+	// FormatArray renders elems (already split out of a Postgres array
+	// text literal), typed as elemType, as a dialect literal.
+	FormatArray(elemType string, elems []string) string
+	// FormatJSON renders raw JSON bytes as a quoted dialect literal.
+	FormatJSON(raw []byte) string
+	// TypeAlias maps a Postgres information_schema data_type (e.g.
+	// "character varying") to the type name this dialect's CREATE TABLE
+	// should use instead.
+	TypeAlias(pgType string) string
+}
+
+// bareIdentRe matches an identifier that needs no quoting: lowercase
+// letters, digits, and underscores, not starting with a digit.
+var bareIdentRe = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// quoteIfNeeded quotes name via dialect.QuoteIdent only when it's a
+// dialect reserved word or isn't a valid bare identifier; otherwise it's
+// returned as-is, matching how pg_dump and most dump tools avoid
+// cluttering output with unnecessary quoting.
+func quoteIfNeeded(dialect Dialect, name string) string {
+	if dialect.IsReserved(name) || !bareIdentRe.MatchString(name) {
+		return dialect.QuoteIdent(name)
+	}
+	return name
+}
+
+// parsePostgresArrayLiteral splits a Postgres array text literal like
+// "{1,2,3}" or `{foo,"bar,baz",NULL}` into its element substrings,
+// unescaping quoted elements. It only handles one level of nesting, which
+// covers every array column GetTableData encounters (arrays of arrays are
+// rare enough not to be worth the extra parsing complexity here).
+func parsePostgresArrayLiteral(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "{")
+	s = strings.TrimSuffix(s, "}")
+	if s == "" {
+		return nil
+	}
+
+	var elems []string
+	var cur strings.Builder
+	inQuotes := false
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ',' && !inQuotes:
+			elems = append(elems, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	elems = append(elems, cur.String())
+	return elems
+}
+
+// formatValueTyped is formatValue's type-aware counterpart: it consults
+// kind (from Dialect.ColumnTypeKind) to render bytes, timestamps, JSON,
+// and arrays as dialect-correct literals, instead of formatValue's %v
+// fallback, which corrupts all four on round-trip. Everything else
+// (numbers, bools, plain text, and text-typed columns like NUMERIC, UUID,
+// and INET) still goes through formatValue.
+func formatValueTyped(val interface{}, kind string, dialect Dialect, loc *time.Location) string {
+	if val == nil {
+		return "NULL"
+	}
+	switch kind {
+	case "bytes":
+		if b, ok := val.([]byte); ok {
+			return dialect.FormatBytes(b)
+		}
+	case "time":
+		if t, ok := val.(time.Time); ok {
+			return dialect.FormatTime(t, loc)
+		}
+	case "json":
+		switch v := val.(type) {
+		case []byte:
+			return dialect.FormatJSON(v)
+		case string:
+			return dialect.FormatJSON([]byte(v))
+		}
+	case "array":
+		if s, ok := val.(string); ok {
+			return dialect.FormatArray("text", parsePostgresArrayLiteral(s))
+		}
+	}
+	return formatValue(val)
+}
+
+// PostgresDialect targets a Postgres restore, the database
+// DBDumpService's own source is always in. It's the default dialect.
+type PostgresDialect struct{}
+
+func (PostgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (PostgresDialect) IsReserved(word string) bool {
+	_, ok := postgresReservedWords[strings.ToUpper(word)]
+	return ok
+}
+
+func (PostgresDialect) ColumnTypeKind(dbType string) string {
+	switch strings.ToUpper(dbType) {
+	case "BYTEA":
+		return "bytes"
+	case "TIMESTAMP", "TIMESTAMPTZ", "DATE", "TIME", "TIMETZ":
+		return "time"
+	case "JSON", "JSONB":
+		return "json"
+	default:
+		if strings.HasPrefix(dbType, "_") {
+			return "array"
+		}
+		return ""
+	}
+}
+
+func (PostgresDialect) FormatBytes(b []byte) string {
+	return fmt.Sprintf(`'\x%s'`, hex.EncodeToString(b))
+}
+
+func (PostgresDialect) FormatTime(t time.Time, loc *time.Location) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05.999999999Z07:00"))
+}
+
+func (PostgresDialect) FormatArray(elemType string, elems []string) string {
+	quoted := make([]string, len(elems))
+	for i, e := range elems {
+		quoted[i] = "'" + strings.ReplaceAll(e, "'", "''") + "'"
+	}
+	return fmt.Sprintf("ARRAY[%s]::%s[]", strings.Join(quoted, ", "), elemType)
+}
+
+func (PostgresDialect) FormatJSON(raw []byte) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(string(raw), "'", "''"))
+}
+
+func (PostgresDialect) TypeAlias(pgType string) string {
+	return strings.ToUpper(pgType)
+}
+
+// MySQLDialect targets a MySQL/MariaDB restore, for the sql-insert format
+// only (MySQL has no native COPY or array type).
+type MySQLDialect struct{}
+
+func (MySQLDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (MySQLDialect) IsReserved(word string) bool {
+	_, ok := mysqlReservedWords[strings.ToUpper(word)]
+	return ok
+}
+
+func (MySQLDialect) ColumnTypeKind(dbType string) string {
+	return PostgresDialect{}.ColumnTypeKind(dbType)
+}
+
+func (MySQLDialect) FormatBytes(b []byte) string {
+	return fmt.Sprintf("X'%s'", hex.EncodeToString(b))
+}
+
+func (MySQLDialect) FormatTime(t time.Time, loc *time.Location) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05.999999"))
+}
+
+func (MySQLDialect) FormatArray(elemType string, elems []string) string {
+	// MySQL has no native array type; a JSON array is its closest
+	// equivalent.
+	quoted := make([]string, len(elems))
+	for i, e := range elems {
+		b, _ := json.Marshal(e)
+		quoted[i] = string(b)
+	}
+	return fmt.Sprintf("'[%s]'", strings.Join(quoted, ","))
+}
+
+func (MySQLDialect) FormatJSON(raw []byte) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(string(raw), "'", "''"))
+}
+
+func (MySQLDialect) TypeAlias(pgType string) string {
+	switch strings.ToLower(pgType) {
+	case "character varying", "text", "character":
+		return "VARCHAR(255)"
+	case "integer", "int4", "smallint", "int2":
+		return "INT"
+	case "bigint", "int8":
+		return "BIGINT"
+	case "boolean":
+		return "TINYINT(1)"
+	case "timestamp without time zone", "timestamp with time zone":
+		return "DATETIME"
+	case "double precision", "real":
+		return "DOUBLE"
+	case "numeric":
+		return "DECIMAL(38,10)"
+	case "jsonb", "json":
+		return "JSON"
+	case "uuid":
+		return "CHAR(36)"
+	case "bytea":
+		return "BLOB"
+	default:
+		return strings.ToUpper(pgType)
+	}
+}
+
+// postgresReservedWords lists the keywords most likely to appear as
+// column or table names in practice; IsReserved isn't meant to be an
+// exhaustive copy of Postgres's keyword table.
+var postgresReservedWords = map[string]struct{}{
+	"SELECT": {}, "FROM": {}, "WHERE": {}, "TABLE": {}, "ORDER": {}, "GROUP": {},
+	"USER": {}, "ROLE": {}, "PRIMARY": {}, "FOREIGN": {}, "KEY": {}, "CHECK": {},
+	"DEFAULT": {}, "NULL": {}, "UNIQUE": {}, "REFERENCES": {}, "GRANT": {},
+	"LIMIT": {}, "OFFSET": {}, "UNION": {}, "ALL": {}, "AND": {}, "OR": {}, "NOT": {},
+	"CAST": {}, "CASE": {}, "WHEN": {}, "THEN": {}, "ELSE": {}, "END": {},
+	"COLUMN": {}, "CONSTRAINT": {}, "CREATE": {}, "DROP": {}, "ALTER": {}, "INTO": {},
+}
+
+// mysqlReservedWords lists the keywords most likely to appear as column
+// or table names in practice; IsReserved isn't meant to be an exhaustive
+// copy of MySQL's reserved word list.
+var mysqlReservedWords = map[string]struct{}{
+	"SELECT": {}, "FROM": {}, "WHERE": {}, "TABLE": {}, "ORDER": {}, "GROUP": {},
+	"KEY": {}, "PRIMARY": {}, "FOREIGN": {}, "CHECK": {}, "DEFAULT": {}, "NULL": {},
+	"UNIQUE": {}, "REFERENCES": {}, "LIMIT": {}, "OFFSET": {}, "UNION": {}, "ALL": {},
+	"AND": {}, "OR": {}, "NOT": {}, "CASE": {}, "WHEN": {}, "THEN": {}, "ELSE": {},
+	"CONDITION": {}, "COLUMN": {}, "CONSTRAINT": {}, "CREATE": {}, "DROP": {},
+	"ALTER": {}, "INTO": {}, "USE": {}, "INDEX": {}, "MATCH": {}, "RANGE": {},
+}
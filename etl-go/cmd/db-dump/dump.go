@@ -0,0 +1,621 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// DumpOptions configures DumpDatabaseWithOptions: which wire format to
+// encode rows in, how many tables to dump concurrently, whether to
+// compress output, and whether to roll output into size-bounded part
+// files.
+type DumpOptions struct {
+	// Format is one of "sql-insert" (default), "copy", "csv", or
+	// "parquet".
+	Format string
+	// Jobs is how many tables to dump concurrently. 1 (the default)
+	// dumps sequentially into a single output file; >1 switches to a
+	// directory of one file (or file series) per table, since
+	// concurrent workers can't safely interleave writes into one file.
+	Jobs int
+	// Compress is "gzip", "zstd", or empty for no compression.
+	Compress string
+	// SplitSize rolls output into numbered "<name>.partNNN<ext>" files
+	// once the current part reaches this many bytes. <= 0 disables
+	// splitting.
+	SplitSize int64
+}
+
+// DumpDatabase is the legacy entry point: a full, single-file, sequential
+// SQL-insert dump with no compression or splitting.
+func (dbs *DBDumpService) DumpDatabase(outputFile string) error {
+	return dbs.DumpDatabaseWithOptions(outputFile, DumpOptions{Format: "sql-insert", Jobs: 1})
+}
+
+// DumpDatabaseWithOptions dumps every table in the database according to
+// opts. Parquet dumps and dumps with opts.Jobs > 1 write one file per
+// table into the directory named by outputFile; everything else streams
+// a single (optionally compressed and split) file at outputFile.
+func (dbs *DBDumpService) DumpDatabaseWithOptions(outputFile string, opts DumpOptions) error {
+	if opts.Jobs <= 0 {
+		opts.Jobs = 1
+	}
+	if opts.Format == "" {
+		opts.Format = "sql-insert"
+	}
+
+	tableNames, err := dbs.GetTableNames()
+	if err != nil {
+		return fmt.Errorf("failed to get table names: %v", err)
+	}
+
+	if opts.Format == "parquet" || opts.Jobs > 1 {
+		return dbs.dumpDatabaseDirectory(outputFile, opts, tableNames)
+	}
+	return dbs.dumpDatabaseSingleFile(outputFile, opts, tableNames)
+}
+
+// dumpDatabaseSingleFile dumps every table, in order, into one output
+// stream inside a single REPEATABLE READ transaction, so every table is
+// dumped from the same consistent snapshot even though they're read one
+// after another.
+func (dbs *DBDumpService) dumpDatabaseSingleFile(outputFile string, opts DumpOptions, tableNames []string) error {
+	newEnc, err := newEncoder(opts.Format, dbs.dialect, dbs.timeZone)
+	if err != nil {
+		return err
+	}
+
+	pw, err := newPartWriter(outputFile, opts.SplitSize, opts.Compress)
+	if err != nil {
+		return err
+	}
+	defer pw.Close()
+
+	if _, err := fmt.Fprint(pw, dumpHeader()); err != nil {
+		return fmt.Errorf("failed to write header: %v", err)
+	}
+
+	ctx := context.Background()
+	tx, err := dbs.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin dump transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range tableNames {
+		schema, err := dbs.GetTableSchema(table)
+		if err != nil {
+			log.Printf("Warning: failed to get schema for table %s: %v", table, err)
+			continue
+		}
+		if _, err := fmt.Fprintf(pw, "-- Schema for table: %s\n%s", table, schema); err != nil {
+			return fmt.Errorf("failed to write schema: %v", err)
+		}
+
+		if err := dbs.dumpTableData(tx, table, newEnc, pw); err != nil {
+			log.Printf("Warning: failed to dump data for table %s: %v", table, err)
+			continue
+		}
+	}
+
+	if _, err := fmt.Fprint(pw, dumpFooter()); err != nil {
+		return fmt.Errorf("failed to write footer: %v", err)
+	}
+
+	log.Printf("Database dump completed successfully: %s", outputFile)
+	return nil
+}
+
+// dumpDatabaseDirectory implements the parallel (opts.Jobs > 1) and
+// parquet dump paths, both of which write one file per table into a
+// directory rather than a single combined output file: concurrent
+// workers writing into a shared file would interleave COPY/CSV blocks
+// from different tables, and parquet's row-group format isn't something
+// multiple tables can share a stream with anyway. This mirrors pg_dump's
+// own --format=directory, which is exactly what its -j flag requires.
+//
+// All workers dump from the same consistent snapshot via
+// dbs.exportSnapshot/beginSnapshotTx, started with REPEATABLE READ and
+// SET TRANSACTION SNAPSHOT, so a table dumped by a later worker can't see
+// writes that happened after an earlier worker already read a different
+// table.
+func (dbs *DBDumpService) dumpDatabaseDirectory(outputDir string, opts DumpOptions, tableNames []string) error {
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create dump directory %s: %v", outputDir, err)
+	}
+
+	if err := dbs.writeSchemaFile(outputDir, tableNames); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	leader, err := dbs.exportSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	defer leader.Release()
+
+	tableCh := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for table := range tableCh {
+				if err := dbs.dumpOneTableToDirectory(ctx, leader, outputDir, table, opts); err != nil {
+					log.Printf("Warning: failed to dump table %s: %v", table, err)
+				}
+			}
+		}()
+	}
+	for _, table := range tableNames {
+		tableCh <- table
+	}
+	close(tableCh)
+	wg.Wait()
+
+	tocPath := filepath.Join(outputDir, "toc.txt")
+	if err := os.WriteFile(tocPath, []byte(strings.Join(tableNames, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tocPath, err)
+	}
+
+	log.Printf("Database dump completed successfully: %s", outputDir)
+	return nil
+}
+
+// writeSchemaFile writes every table's CREATE TABLE statement into a
+// single schema.sql up front, before any worker starts, so the directory
+// dump doesn't need to coordinate concurrent writers for schema too.
+func (dbs *DBDumpService) writeSchemaFile(outputDir string, tableNames []string) error {
+	schemaPath := filepath.Join(outputDir, "schema.sql")
+	schemaFile, err := os.Create(schemaPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", schemaPath, err)
+	}
+	defer schemaFile.Close()
+
+	for _, table := range tableNames {
+		schema, err := dbs.GetTableSchema(table)
+		if err != nil {
+			log.Printf("Warning: failed to get schema for table %s: %v", table, err)
+			continue
+		}
+		if _, err := fmt.Fprintf(schemaFile, "-- Schema for table: %s\n%s", table, schema); err != nil {
+			return fmt.Errorf("failed to write schema: %v", err)
+		}
+	}
+	return nil
+}
+
+// dumpOneTableToDirectory dumps a single table into outputDir, as
+// <table>.parquet for the parquet format or <table><ext> (optionally
+// split and/or compressed) for everything else.
+func (dbs *DBDumpService) dumpOneTableToDirectory(ctx context.Context, leader *snapshotLeader, outputDir, table string, opts DumpOptions) error {
+	if opts.Format == "parquet" {
+		return dbs.dumpTableParquet(ctx, leader, outputDir, table)
+	}
+
+	tx, err := dbs.beginSnapshotTx(ctx, leader)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	newEnc, err := newEncoder(opts.Format, dbs.dialect, dbs.timeZone)
+	if err != nil {
+		return err
+	}
+
+	pw, err := newPartWriter(filepath.Join(outputDir, table+formatExtension(opts.Format)), opts.SplitSize, opts.Compress)
+	if err != nil {
+		return err
+	}
+	defer pw.Close()
+
+	return dbs.dumpTableData(tx, table, newEnc, pw)
+}
+
+// dumpTableData streams table's rows through a freshly-constructed
+// encoder directly to w, one row at a time, so a single table's dump
+// never needs to hold more than one row in memory at once.
+func (dbs *DBDumpService) dumpTableData(tx *sql.Tx, table string, newEnc func() TableEncoder, w io.Writer) error {
+	rows, err := tx.Query(fmt.Sprintf("SELECT * FROM %s", quoteIdent(table)))
+	if err != nil {
+		return fmt.Errorf("failed to query table %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get column info for table %s: %v", table, err)
+	}
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("failed to get column types for table %s: %v", table, err)
+	}
+	kinds := make([]string, len(colTypes))
+	for i, ct := range colTypes {
+		kinds[i] = dbs.dialect.ColumnTypeKind(ct.DatabaseTypeName())
+	}
+
+	enc := newEnc()
+	if err := enc.WriteHeader(w, table, columns); err != nil {
+		return fmt.Errorf("failed to write header for table %s: %v", table, err)
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("failed to scan row in table %s: %v", table, err)
+		}
+		if err := enc.WriteRow(w, columns, kinds, values); err != nil {
+			return fmt.Errorf("failed to write row for table %s: %v", table, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows in table %s: %v", table, err)
+	}
+
+	return enc.WriteFooter(w)
+}
+
+// dumpTableParquet writes table to <outputDir>/<table>.parquet using a
+// flat, all-string JSON schema (parquet-go's JSON writer), mirroring the
+// raw-lake parquet output the main ETL service produces, so the file can
+// be ingested the same way.
+func (dbs *DBDumpService) dumpTableParquet(ctx context.Context, leader *snapshotLeader, outputDir, table string) error {
+	tx, err := dbs.beginSnapshotTx(ctx, leader)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s", quoteIdent(table)))
+	if err != nil {
+		return fmt.Errorf("failed to query table %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get column info for table %s: %v", table, err)
+	}
+
+	schemaJSON, err := parquetJSONSchema(columns)
+	if err != nil {
+		return err
+	}
+
+	parquetPath := filepath.Join(outputDir, table+".parquet")
+	fw, err := local.NewLocalFileWriter(parquetPath)
+	if err != nil {
+		return fmt.Errorf("failed to open parquet file %s: %v", parquetPath, err)
+	}
+	defer fw.Close()
+
+	pw, err := writer.NewJSONWriter(schemaJSON, fw, 4)
+	if err != nil {
+		return fmt.Errorf("failed to create parquet writer for %s: %v", parquetPath, err)
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("failed to scan row in table %s: %v", table, err)
+		}
+		record := make(map[string]string, len(columns))
+		for i, col := range columns {
+			record[col] = plainValue(values[i])
+		}
+		rowJSON, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal parquet row for table %s: %v", table, err)
+		}
+		if err := pw.Write(string(rowJSON)); err != nil {
+			return fmt.Errorf("failed to write parquet row for table %s: %v", table, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows in table %s: %v", table, err)
+	}
+
+	if err := pw.WriteStop(); err != nil {
+		return fmt.Errorf("failed to finalize parquet file %s: %v", parquetPath, err)
+	}
+	return nil
+}
+
+// parquetJSONSchema builds the flat, all-UTF8 JSON schema parquet-go's
+// JSON writer needs: every column becomes an optional BYTE_ARRAY/UTF8
+// field, matching plainValue's string-only output.
+func parquetJSONSchema(columns []string) (string, error) {
+	type field struct {
+		Tag string `json:"Tag"`
+	}
+	type schema struct {
+		Tag    string  `json:"Tag"`
+		Fields []field `json:"Fields"`
+	}
+
+	s := schema{Tag: "name=root, repetitiontype=REQUIRED"}
+	for _, col := range columns {
+		s.Fields = append(s.Fields, field{
+			Tag: fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL", col),
+		})
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to build parquet schema: %v", err)
+	}
+	return string(b), nil
+}
+
+// snapshotLeader holds open the transaction that exported a consistent
+// database snapshot, so every worker transaction that imports it via SET
+// TRANSACTION SNAPSHOT sees exactly the same data. The exported snapshot
+// ID is only valid while this transaction stays open; call Release once
+// every worker has finished.
+type snapshotLeader struct {
+	conn *sql.Conn
+	tx   *sql.Tx
+	id   string
+}
+
+// exportSnapshot begins a REPEATABLE READ transaction on a dedicated
+// connection and exports its snapshot via pg_export_snapshot, so parallel
+// dump workers can each import it and read a single consistent point in
+// time across every table.
+func (dbs *DBDumpService) exportSnapshot(ctx context.Context) (*snapshotLeader, error) {
+	conn, err := dbs.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection for snapshot export: %v", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to begin snapshot-export transaction: %v", err)
+	}
+
+	var id string
+	if err := tx.QueryRowContext(ctx, `SELECT pg_export_snapshot()`).Scan(&id); err != nil {
+		tx.Rollback()
+		conn.Close()
+		return nil, fmt.Errorf("failed to export snapshot: %v", err)
+	}
+
+	return &snapshotLeader{conn: conn, tx: tx, id: id}, nil
+}
+
+// Release ends the leader transaction, invalidating its exported
+// snapshot. Call only after every worker that imported the snapshot has
+// finished.
+func (sl *snapshotLeader) Release() {
+	sl.tx.Rollback()
+	sl.conn.Close()
+}
+
+// beginSnapshotTx starts a REPEATABLE READ transaction that imports sl's
+// exported snapshot, so it reads the identical database state sl does.
+func (dbs *DBDumpService) beginSnapshotTx(ctx context.Context, sl *snapshotLeader) (*sql.Tx, error) {
+	tx, err := dbs.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin worker transaction: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`SET TRANSACTION SNAPSHOT '%s'`, sl.id)); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to import snapshot %s: %v", sl.id, err)
+	}
+	return tx, nil
+}
+
+// dumpHeader returns the preamble written to every single-file dump.
+func dumpHeader() string {
+	return `-- Database Dump Generated by Data Lakehouse ETL System
+-- Exported on: ` + time.Now().UTC().Format("2006-01-02 15:04:05") + ` UTC
+-- This dump contains schema and data for sharing with visualization tools
+-- To import: psql -d database_name -f dump_file.sql
+
+-- Disable triggers and constraints during import
+SET session_replication_role = replica;
+
+`
+}
+
+// dumpFooter returns the text written after every single-file dump.
+func dumpFooter() string {
+	return `
+-- Re-enable triggers and constraints
+SET session_replication_role = DEFAULT;
+
+-- End of dump
+`
+}
+
+// partWriter is an io.WriteCloser that transparently rolls output into
+// numbered "<base>.partNNN<ext>" files once the current part reaches
+// splitSize bytes, and optionally gzip/zstd-compresses each part, so a
+// single table's dump never produces one unbounded file. splitSize <= 0
+// disables splitting: a single part is used, named "<base><ext>" (plus
+// ".gz"/".zst" when compressed) with no "partNNN" suffix.
+type partWriter struct {
+	base      string
+	ext       string
+	splitSize int64
+	compress  string
+
+	part    int
+	written int64
+	cur     io.WriteCloser
+}
+
+func newPartWriter(outputFile string, splitSize int64, compress string) (*partWriter, error) {
+	switch compress {
+	case "", "gzip", "zstd":
+	default:
+		return nil, fmt.Errorf("unsupported --compress value %q (want gzip or zstd)", compress)
+	}
+
+	ext := filepath.Ext(outputFile)
+	base := strings.TrimSuffix(outputFile, ext)
+	pw := &partWriter{base: base, ext: ext, splitSize: splitSize, compress: compress}
+	if err := pw.rotate(); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// partName returns the file name for the current part: "<base><ext>" (no
+// "partNNN" suffix) for an unsplit dump's only part, or
+// "<base>.partNNN<ext>" otherwise, with a trailing ".gz"/".zst" when
+// compressed.
+func (pw *partWriter) partName() string {
+	name := pw.base + pw.ext
+	if pw.splitSize > 0 {
+		name = fmt.Sprintf("%s.part%03d%s", pw.base, pw.part, pw.ext)
+	}
+	switch pw.compress {
+	case "gzip":
+		name += ".gz"
+	case "zstd":
+		name += ".zst"
+	}
+	return name
+}
+
+func (pw *partWriter) rotate() error {
+	if pw.cur != nil {
+		if err := pw.cur.Close(); err != nil {
+			return fmt.Errorf("failed to close dump part: %v", err)
+		}
+	}
+
+	name := pw.partName()
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create dump part %s: %v", name, err)
+	}
+
+	switch pw.compress {
+	case "gzip":
+		pw.cur = &gzipWriteCloser{gz: gzip.NewWriter(f), f: f}
+	case "zstd":
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to create zstd writer for %s: %v", name, err)
+		}
+		pw.cur = &zstdWriteCloser{zw: zw, f: f}
+	default:
+		pw.cur = f
+	}
+
+	pw.written = 0
+	pw.part++
+	return nil
+}
+
+func (pw *partWriter) Write(p []byte) (int, error) {
+	if pw.splitSize > 0 && pw.written >= pw.splitSize {
+		if err := pw.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := pw.cur.Write(p)
+	pw.written += int64(n)
+	return n, err
+}
+
+func (pw *partWriter) Close() error {
+	if pw.cur == nil {
+		return nil
+	}
+	return pw.cur.Close()
+}
+
+// gzipWriteCloser closes both the gzip stream and its underlying file.
+type gzipWriteCloser struct {
+	gz *gzip.Writer
+	f  *os.File
+}
+
+func (g *gzipWriteCloser) Write(p []byte) (int, error) { return g.gz.Write(p) }
+
+func (g *gzipWriteCloser) Close() error {
+	if err := g.gz.Close(); err != nil {
+		g.f.Close()
+		return err
+	}
+	return g.f.Close()
+}
+
+// zstdWriteCloser closes both the zstd stream and its underlying file.
+type zstdWriteCloser struct {
+	zw *zstd.Encoder
+	f  *os.File
+}
+
+func (z *zstdWriteCloser) Write(p []byte) (int, error) { return z.zw.Write(p) }
+
+func (z *zstdWriteCloser) Close() error {
+	if err := z.zw.Close(); err != nil {
+		z.f.Close()
+		return err
+	}
+	return z.f.Close()
+}
+
+// parseSplitSize parses a human --split-size value like "256MB", "1GB",
+// or a bare byte count. An empty string means no splitting.
+func parseSplitSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := float64(1)
+	upper := strings.ToUpper(s)
+	switch {
+	case strings.HasSuffix(upper, "GB"):
+		multiplier = 1 << 30
+		s = s[:len(s)-2]
+	case strings.HasSuffix(upper, "MB"):
+		multiplier = 1 << 20
+		s = s[:len(s)-2]
+	case strings.HasSuffix(upper, "KB"):
+		multiplier = 1 << 10
+		s = s[:len(s)-2]
+	case strings.HasSuffix(upper, "B"):
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --split-size %q: %v", s, err)
+	}
+	return int64(n * multiplier), nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// TableEncoder streams one table's rows into a specific on-disk dump
+// format. A fresh TableEncoder is created per table (see newEncoder) so
+// implementations may hold per-table state, such as CSV's underlying
+// *csv.Writer.
+type TableEncoder interface {
+	// WriteHeader is called once before any rows, e.g. to emit a CSV
+	// header row or a COPY "... FROM stdin;" line.
+	WriteHeader(w io.Writer, table string, columns []string) error
+	// WriteRow is called once per row, in column order. kinds holds each
+	// column's Dialect.ColumnTypeKind classification, parallel to
+	// columns and values, so an encoder can render bytes/time/json/array
+	// columns correctly instead of falling back to a %v-style dump.
+	WriteRow(w io.Writer, columns []string, kinds []string, values []interface{}) error
+	// WriteFooter is called once after the last row, e.g. to emit COPY's
+	// terminating "\." or flush a buffered writer.
+	WriteFooter(w io.Writer) error
+}
+
+// newEncoder returns a constructor for format's TableEncoder, bound to
+// dialect and loc for type-aware value formatting. A constructor, rather
+// than a shared instance, is returned so parallel workers dumping
+// different tables each get their own encoder state.
+func newEncoder(format string, dialect Dialect, loc *time.Location) (func() TableEncoder, error) {
+	switch format {
+	case "", "sql-insert":
+		return func() TableEncoder { return &sqlInsertEncoder{dialect: dialect, loc: loc} }, nil
+	case "copy":
+		return func() TableEncoder { return &copyEncoder{loc: loc} }, nil
+	case "csv":
+		return func() TableEncoder { return &csvEncoder{loc: loc} }, nil
+	default:
+		return nil, fmt.Errorf("unsupported --format value %q (want sql-insert, copy, csv, or parquet)", format)
+	}
+}
+
+// formatExtension returns the file extension DumpDatabaseWithOptions
+// should use for a table dumped in format, when writing one file per
+// table (parallel or parquet dumps).
+func formatExtension(format string) string {
+	switch format {
+	case "copy":
+		return ".copy.sql"
+	case "csv":
+		return ".csv"
+	case "parquet":
+		return ".parquet"
+	default:
+		return ".sql"
+	}
+}
+
+// sqlInsertEncoder is the original, human-readable "INSERT INTO ... VALUES
+// (...);" format. Values are formatted via dialect/loc so BYTEA, JSONB,
+// arrays, and TIMESTAMPTZ columns round-trip correctly instead of going
+// through formatValue's %v fallback.
+type sqlInsertEncoder struct {
+	table   string
+	dialect Dialect
+	loc     *time.Location
+}
+
+func (e *sqlInsertEncoder) WriteHeader(w io.Writer, table string, columns []string) error {
+	e.table = table
+	_, err := fmt.Fprintf(w, "-- Data for table: %s\n", table)
+	return err
+}
+
+func (e *sqlInsertEncoder) WriteRow(w io.Writer, columns []string, kinds []string, values []interface{}) error {
+	quotedCols := make([]string, len(columns))
+	for i, c := range columns {
+		quotedCols[i] = quoteIdent(c)
+	}
+	valuesStr := make([]string, len(values))
+	for i, v := range values {
+		valuesStr[i] = formatValueTyped(v, kinds[i], e.dialect, e.loc)
+	}
+	_, err := fmt.Fprintf(w, "INSERT INTO %s (%s) VALUES (%s);\n",
+		quoteIdent(e.table), strings.Join(quotedCols, ", "), strings.Join(valuesStr, ", "))
+	return err
+}
+
+func (e *sqlInsertEncoder) WriteFooter(w io.Writer) error {
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// copyCSVTimeLayout renders a time.Time the way Postgres's COPY text
+// format and CSV import both expect timestamp/timestamptz columns:
+// unquoted (unlike formatValueTyped's SQL-literal form), in loc, with a
+// numeric UTC offset rather than a zone abbreviation.
+const copyCSVTimeLayout = "2006-01-02 15:04:05.999999-07"
+
+// formatTimeUnquoted renders t, converted into loc, for the copy/csv
+// encoders. Go's %v/time.Time.String() default ("... -0700 MST") embeds a
+// zone-name token neither COPY nor CSV import can parse; this layout
+// matches what Postgres itself emits for a COPY TO.
+func formatTimeUnquoted(t time.Time, loc *time.Location) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return t.Format(copyCSVTimeLayout)
+}
+
+// copyEncoder emits a "COPY ... FROM stdin;" block in PostgreSQL's COPY
+// text format, for fast restore via `psql -f` or a direct pq.CopyIn.
+type copyEncoder struct {
+	loc *time.Location
+}
+
+func (e *copyEncoder) WriteHeader(w io.Writer, table string, columns []string) error {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	_, err := fmt.Fprintf(w, "COPY %s (%s) FROM stdin;\n", quoteIdent(table), strings.Join(quoted, ", "))
+	return err
+}
+
+func (e *copyEncoder) WriteRow(w io.Writer, columns []string, kinds []string, values []interface{}) error {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		fields[i] = copyFieldText(v, kinds[i], e.loc)
+	}
+	_, err := fmt.Fprintf(w, "%s\n", strings.Join(fields, "\t"))
+	return err
+}
+
+func (e *copyEncoder) WriteFooter(w io.Writer) error {
+	_, err := fmt.Fprint(w, "\\.\n\n")
+	return err
+}
+
+// copyFieldReplacer escapes the characters COPY's text format treats
+// specially: a literal backslash, tab, and newline.
+var copyFieldReplacer = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// copyFieldText formats val the way COPY's text format expects: \N for
+// NULL, Postgres's own "\x<hex>" external form (with the leading
+// backslash doubled, since COPY text format is itself backslash-escaped)
+// for bytea, the raw JSON text for json/jsonb, loc-converted
+// copyCSVTimeLayout text for timestamp/timestamptz, otherwise the value's
+// text form with backslash/tab/newline escaped.
+func copyFieldText(val interface{}, kind string, loc *time.Location) string {
+	if val == nil {
+		return `\N`
+	}
+	switch kind {
+	case "bytes":
+		if b, ok := val.([]byte); ok {
+			return `\\x` + hex.EncodeToString(b)
+		}
+	case "time":
+		if t, ok := val.(time.Time); ok {
+			return formatTimeUnquoted(t, loc)
+		}
+	case "json":
+		switch v := val.(type) {
+		case []byte:
+			return copyFieldReplacer.Replace(string(v))
+		case string:
+			return copyFieldReplacer.Replace(v)
+		}
+	}
+	return copyFieldReplacer.Replace(plainValue(val))
+}
+
+// csvEncoder emits a standard RFC 4180 CSV file via encoding/csv.
+type csvEncoder struct {
+	cw  *csv.Writer
+	loc *time.Location
+}
+
+func (e *csvEncoder) WriteHeader(w io.Writer, table string, columns []string) error {
+	e.cw = csv.NewWriter(w)
+	return e.cw.Write(columns)
+}
+
+func (e *csvEncoder) WriteRow(w io.Writer, columns []string, kinds []string, values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = csvFieldText(v, kinds[i], e.loc)
+	}
+	return e.cw.Write(record)
+}
+
+// csvFieldText mirrors copyFieldText's bytes/json/time handling, minus
+// COPY's backslash escaping (encoding/csv already handles quoting commas,
+// quotes, and newlines for us).
+func csvFieldText(val interface{}, kind string, loc *time.Location) string {
+	if val == nil {
+		return ""
+	}
+	switch kind {
+	case "bytes":
+		if b, ok := val.([]byte); ok {
+			return `\x` + hex.EncodeToString(b)
+		}
+	case "time":
+		if t, ok := val.(time.Time); ok {
+			return formatTimeUnquoted(t, loc)
+		}
+	case "json":
+		switch v := val.(type) {
+		case []byte:
+			return string(v)
+		case string:
+			return v
+		}
+	}
+	return plainValue(val)
+}
+
+func (e *csvEncoder) WriteFooter(w io.Writer) error {
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+// plainValue renders val as plain text with no format-specific quoting or
+// escaping, for formats (CSV, parquet) that apply their own.
+func plainValue(val interface{}) string {
+	if val == nil {
+		return ""
+	}
+	switch v := val.(type) {
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// quoteIdent double-quotes a Postgres identifier, escaping any embedded
+// double quotes.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
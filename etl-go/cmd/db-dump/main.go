@@ -2,10 +2,13 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -13,6 +16,14 @@ import (
 // DBDumpService handles database dump operations
 type DBDumpService struct {
 	db *sql.DB
+
+	// dialect controls how GetTableSchema's CREATE TABLE text and the
+	// sql-insert format's literals are rendered; defaults to
+	// PostgresDialect. See SetDialect.
+	dialect Dialect
+	// timeZone is the session time zone FormatTime converts timestamps
+	// into before rendering them; defaults to UTC. See SetTimeZone.
+	timeZone *time.Location
 }
 
 // NewDBDumpService creates a new database dump service
@@ -30,10 +41,24 @@ func NewDBDumpService(dbHost, dbName, dbUser, dbPassword string) (*DBDumpService
 	}
 
 	return &DBDumpService{
-		db: db,
+		db:       db,
+		dialect:  PostgresDialect{},
+		timeZone: time.UTC,
 	}, nil
 }
 
+// SetDialect changes the dialect GetTableSchema and the sql-insert format
+// render output for, e.g. to MySQLDialect{} when dumping for a MySQL
+// target.
+func (dbs *DBDumpService) SetDialect(d Dialect) {
+	dbs.dialect = d
+}
+
+// SetTimeZone changes the session time zone timestamps are rendered in.
+func (dbs *DBDumpService) SetTimeZone(loc *time.Location) {
+	dbs.timeZone = loc
+}
+
 // Close closes the database connection
 func (dbs *DBDumpService) Close() {
 	if dbs.db != nil {
@@ -98,7 +123,7 @@ func (dbs *DBDumpService) GetTableSchema(tableName string) (string, error) {
 			return "", err
 		}
 		
-		colDef := fmt.Sprintf("    \"%s\" %s", colName, strings.ToUpper(dataType))
+		colDef := fmt.Sprintf("    %s %s", quoteIfNeeded(dbs.dialect, colName), dbs.dialect.TypeAlias(dataType))
 		if isNullable == "NO" {
 			colDef += " NOT NULL"
 		}
@@ -132,10 +157,10 @@ func (dbs *DBDumpService) GetTableSchema(tableName string) (string, error) {
 		if err := pkRows.Scan(&colName); err != nil {
 			return "", err
 		}
-		pkColumns = append(pkColumns, fmt.Sprintf("\"%s\"", colName))
+		pkColumns = append(pkColumns, quoteIfNeeded(dbs.dialect, colName))
 	}
 
-	schema := fmt.Sprintf("CREATE TABLE IF NOT EXISTS \"%s\" (\n", tableName)
+	schema := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", quoteIfNeeded(dbs.dialect, tableName))
 	schema += strings.Join(columns, ",\n")
 	
 	if len(pkColumns) > 0 {
@@ -147,60 +172,6 @@ func (dbs *DBDumpService) GetTableSchema(tableName string) (string, error) {
 	return schema, nil
 }
 
-// GetTableData retrieves all data from a specific table
-func (dbs *DBDumpService) GetTableData(tableName string) (string, error) {
-	query := fmt.Sprintf("SELECT * FROM \"%s\"", tableName)
-	
-	rows, err := dbs.db.Query(query)
-	if err != nil {
-		return "", fmt.Errorf("failed to query table %s: %v", tableName, err)
-	}
-	defer rows.Close()
-
-	// Get column information
-	columns, err := rows.Columns()
-	if err != nil {
-		return "", fmt.Errorf("failed to get column info for table %s: %v", tableName, err)
-	}
-
-	// Build INSERT statements
-	var inserts []string
-	
-	for rows.Next() {
-		// Create a slice of interface{} to hold the values
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
-		
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return "", fmt.Errorf("failed to scan row in table %s: %v", tableName, err)
-		}
-		
-		// Build VALUES part of INSERT
-		var valuesStr []string
-		for _, val := range values {
-			strVal := formatValue(val)
-			valuesStr = append(valuesStr, strVal)
-		}
-		
-		insert := fmt.Sprintf("INSERT INTO \"%s\" (%s) VALUES (%s);", 
-			tableName, 
-			"\"" + strings.Join(columns, "\", \"") + "\"",
-			strings.Join(valuesStr, ", "))
-		
-		inserts = append(inserts, insert)
-	}
-	
-	if err := rows.Err(); err != nil {
-		return "", fmt.Errorf("error iterating rows in table %s: %v", tableName, err)
-	}
-	
-	return strings.Join(inserts, "\n") + "\n\n", nil
-}
-
 // formatValue formats a value for SQL insertion
 func formatValue(val interface{}) string {
 	if val == nil {
@@ -229,104 +200,42 @@ func formatValue(val interface{}) string {
 	}
 }
 
-// DumpDatabase creates a complete SQL dump of the database
-func (dbs *DBDumpService) DumpDatabase(outputFile string) error {
-	tableNames, err := dbs.GetTableNames()
-	if err != nil {
-		return fmt.Errorf("failed to get table names: %v", err)
-	}
-
-	file, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
-	}
-	defer file.Close()
-
-	// Write header
-	header := `-- Database Dump Generated by Data Lakehouse ETL System
--- Exported on: ` + fmt.Sprintf("%s\n\n", "2025-11-19 10:00:00") + `
--- This dump contains schema and data for sharing with visualization tools
--- To import: psql -d database_name -f dump_file.sql
-
--- Disable triggers and constraints during import
-SET session_replication_role = replica;
-
-`
-	
-	if _, err := file.WriteString(header); err != nil {
-		return fmt.Errorf("failed to write header: %v", err)
-	}
-
-	// Dump each table's schema and data
-	for _, tableName := range tableNames {
-		// Write table schema
-		schema, err := dbs.GetTableSchema(tableName)
-		if err != nil {
-			log.Printf("Warning: failed to get schema for table %s: %v", tableName, err)
-			continue
-		}
-		
-		if _, err := file.WriteString("-- Schema for table: " + tableName + "\n"); err != nil {
-			return fmt.Errorf("failed to write schema comment: %v", err)
-		}
-		
-		if _, err := file.WriteString(schema); err != nil {
-			return fmt.Errorf("failed to write schema: %v", err)
-		}
-
-		// Write table data
-		data, err := dbs.GetTableData(tableName)
-		if err != nil {
-			log.Printf("Warning: failed to get data for table %s: %v", tableName, err)
-			continue
-		}
-		
-		if _, err := file.WriteString("-- Data for table: " + tableName + "\n"); err != nil {
-			return fmt.Errorf("failed to write data comment: %v", err)
-		}
-		
-		if _, err := file.WriteString(data); err != nil {
-			return fmt.Errorf("failed to write data: %v", err)
-		}
-	}
-
-	// Write footer
-	footer := `
--- Re-enable triggers and constraints
-SET session_replication_role = DEFAULT;
-
--- End of dump
-`
-	
-	if _, err := file.WriteString(footer); err != nil {
-		return fmt.Errorf("failed to write footer: %v", err)
-	}
-
-	log.Printf("Database dump completed successfully: %s", outputFile)
-	return nil
-}
-
 func main() {
 	dbHost := os.Getenv("DB_HOST")
 	if dbHost == "" {
 		dbHost = "localhost"
 	}
-	
+
 	dbName := os.Getenv("DB_NAME")
 	if dbName == "" {
 		log.Fatal("DB_NAME environment variable must be set")
 	}
-	
+
 	dbUser := os.Getenv("DB_USER")
 	if dbUser == "" {
 		log.Fatal("DB_USER environment variable must be set")
 	}
-	
+
 	dbPassword := os.Getenv("DB_PASSWORD")
 	if dbPassword == "" {
 		log.Fatal("DB_PASSWORD environment variable must be set")
 	}
-	
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(dbHost, dbName, dbUser, dbPassword, os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "dump" {
+		runDumpCommand(dbHost, dbName, dbUser, dbPassword, os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "schedule" {
+		runScheduleCommand(dbHost, dbName, dbUser, dbPassword, os.Args[2:])
+		return
+	}
+
 	outputFile := os.Getenv("OUTPUT_FILE")
 	if outputFile == "" {
 		outputFile = "database_dump.sql"
@@ -341,4 +250,128 @@ func main() {
 	if err := dumpService.DumpDatabase(outputFile); err != nil {
 		log.Fatalf("Failed to dump database: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// runDumpCommand handles "dump [--output path] [--format sql-insert|copy|
+// csv|parquet] [--jobs N] [--compress gzip|zstd] [--split-size 256MB]
+// [--dialect postgres|mysql] [--timezone America/New_York]". --output
+// falls back to OUTPUT_FILE, matching the default (no subcommand)
+// invocation above.
+func runDumpCommand(dbHost, dbName, dbUser, dbPassword string, args []string) {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	output := fs.String("output", "", "output file (single-file formats), or directory with --jobs>1 or --format=parquet")
+	format := fs.String("format", "sql-insert", "dump format: sql-insert, copy, csv, or parquet")
+	jobs := fs.Int("jobs", 1, "number of tables to dump concurrently")
+	compress := fs.String("compress", "", "compress output: gzip, zstd, or empty for none")
+	splitSize := fs.String("split-size", "", "roll output into numbered parts once a file reaches this size, e.g. 256MB")
+	dialectName := fs.String("dialect", "postgres", "target dialect for CREATE TABLE and sql-insert literals: postgres or mysql")
+	timezone := fs.String("timezone", "UTC", "session time zone to render timestamp columns in")
+	fs.Parse(args)
+
+	outputFile := *output
+	if outputFile == "" {
+		outputFile = os.Getenv("OUTPUT_FILE")
+	}
+	if outputFile == "" {
+		outputFile = "database_dump.sql"
+	}
+
+	splitBytes, err := parseSplitSize(*splitSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dialect, err := parseDialectName(*dialectName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		log.Fatalf("invalid --timezone %q: %v", *timezone, err)
+	}
+
+	dumpService, err := NewDBDumpService(dbHost, dbName, dbUser, dbPassword)
+	if err != nil {
+		log.Fatalf("Failed to create dump service: %v", err)
+	}
+	defer dumpService.Close()
+	dumpService.SetDialect(dialect)
+	dumpService.SetTimeZone(loc)
+
+	opts := DumpOptions{Format: *format, Jobs: *jobs, Compress: *compress, SplitSize: splitBytes}
+	if err := dumpService.DumpDatabaseWithOptions(outputFile, opts); err != nil {
+		log.Fatalf("Failed to dump database: %v", err)
+	}
+}
+
+// runMigrateCommand handles the "migrate <verb> [args...]" CLI, dispatching
+// to MigrationService. MIGRATIONS_DIR selects the directory of .up.sql/
+// .down.sql files (default "migrations").
+func runMigrateCommand(dbHost, dbName, dbUser, dbPassword string, args []string) {
+	if len(args) == 0 {
+		log.Fatal("usage: migrate <up|down|redo|status|version|force> [args...]")
+	}
+
+	dumpService, err := NewDBDumpService(dbHost, dbName, dbUser, dbPassword)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dumpService.Close()
+
+	migrationsDir := os.Getenv("MIGRATIONS_DIR")
+	if migrationsDir == "" {
+		migrationsDir = "migrations"
+	}
+	ms := NewMigrationService(dumpService.db, migrationsDir)
+
+	switch args[0] {
+	case "up":
+		if err := ms.Up(); err != nil {
+			log.Fatalf("migrate up failed: %v", err)
+		}
+		log.Println("migrate up completed")
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil {
+				log.Fatalf("invalid migrate down count %q: %v", args[1], err)
+			}
+			n = parsed
+		}
+		if err := ms.Down(n); err != nil {
+			log.Fatalf("migrate down failed: %v", err)
+		}
+		log.Printf("migrate down %d completed", n)
+	case "redo":
+		if err := ms.Redo(); err != nil {
+			log.Fatalf("migrate redo failed: %v", err)
+		}
+		log.Println("migrate redo completed")
+	case "status":
+		if err := ms.Status(); err != nil {
+			log.Fatalf("migrate status failed: %v", err)
+		}
+	case "version":
+		version, err := ms.Version()
+		if err != nil {
+			log.Fatalf("migrate version failed: %v", err)
+		}
+		fmt.Println(version)
+	case "force":
+		if len(args) < 2 {
+			log.Fatal("usage: migrate force <version>")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatalf("invalid migrate force version %q: %v", args[1], err)
+		}
+		if err := ms.Force(version); err != nil {
+			log.Fatalf("migrate force failed: %v", err)
+		}
+		log.Printf("migrate force %d completed", version)
+	default:
+		log.Fatalf("unknown migrate verb %q (expected up, down, redo, status, version, or force)", args[0])
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// dbConn is the subset of *sql.DB and *sql.Conn that ensureSchemaMigrationsTable,
+// appliedVersions, and applyMigration need. withLock passes them a *sql.Conn
+// pinned for the lifetime of the lock; everything else (Status, Version,
+// Force) runs unlocked against ms.db directly.
+type dbConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// migrationFileRe matches a migrations directory entry like
+// 0001_create_fact_sales.up.sql or 0001_create_fact_sales.down.sql.
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change: a version number, a name for
+// humans, and the SQL that applies it (Up) or reverses it (Down).
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationService runs versioned, reversible DDL migrations against the
+// same database DBDumpService dumps. Applied versions are tracked in a
+// schema_migrations table, which DumpDatabase includes like any other
+// table, so a restored database resumes from the right version.
+type MigrationService struct {
+	db  *sql.DB
+	dir string
+}
+
+// NewMigrationService returns a MigrationService that loads .up.sql/
+// .down.sql pairs from dir (e.g. "migrations") and tracks them against db.
+func NewMigrationService(db *sql.DB, dir string) *MigrationService {
+	return &MigrationService{db: db, dir: dir}
+}
+
+// migrationLockID is the pg_advisory_lock key MigrationService holds for
+// the duration of a migrate run, so two runners started against the same
+// database serialize instead of racing on schema_migrations.
+const migrationLockID = 732951
+
+// ensureSchemaMigrationsTable creates schema_migrations if it doesn't
+// already exist.
+func ensureSchemaMigrationsTable(ctx context.Context, conn dbConn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			dirty      BOOLEAN NOT NULL DEFAULT FALSE,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+// withLock runs fn while holding a session-level pg_advisory_lock, so
+// concurrent `migrate` invocations against the same database serialize
+// instead of both applying the same version. A session-level advisory
+// lock is held by the backend connection that acquired it, not by the
+// *sql.DB as a whole, so lock, fn, and unlock all run on one *sql.Conn
+// pinned out of the pool for this call rather than on ms.db, which could
+// hand acquire and release to two different pooled connections and leak
+// the lock.
+func (ms *MigrationService) withLock(fn func(ctx context.Context, conn dbConn) error) error {
+	ctx := context.Background()
+	conn, err := ms.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire a connection for the migration lock: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %v", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	return fn(ctx, conn)
+}
+
+// loadMigrations reads ms.dir and returns every migration with both a
+// .up.sql and .down.sql file present, sorted by version ascending.
+func (ms *MigrationService) loadMigrations() ([]Migration, error) {
+	entries, err := os.ReadDir(ms.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory %s: %v", ms.dir, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		body, err := os.ReadFile(filepath.Join(ms.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %v", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+		if match[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	var migrations []Migration
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s is missing its up or down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// appliedVersions returns the set of versions recorded in
+// schema_migrations, and which of those are marked dirty.
+func appliedVersions(ctx context.Context, conn dbConn) (applied map[int64]bool, dirty map[int64]bool, err error) {
+	if err := ensureSchemaMigrationsTable(ctx, conn); err != nil {
+		return nil, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, `SELECT version, dirty FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied = make(map[int64]bool)
+	dirty = make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isDirty bool
+		if err := rows.Scan(&version, &isDirty); err != nil {
+			return nil, nil, err
+		}
+		applied[version] = true
+		dirty[version] = isDirty
+	}
+	return applied, dirty, rows.Err()
+}
+
+// applyMigration runs stmt in a transaction on conn, then marks version
+// applied (or, if markApplied is false, removes it from
+// schema_migrations, for Down). On failure, version is left marked dirty
+// so a later `migrate force` is required before Up/Down will touch it
+// again. conn is the same pinned connection withLock acquired the
+// migration lock on, so the dirty-marking write below is visible to the
+// next `migrate` run the moment this one releases the lock.
+func applyMigration(ctx context.Context, conn dbConn, m Migration, stmt string, markApplied bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %d: %v", m.Version, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		tx.Rollback()
+		if _, dirtyErr := conn.ExecContext(ctx, `
+			INSERT INTO schema_migrations (version, dirty) VALUES ($1, TRUE)
+			ON CONFLICT (version) DO UPDATE SET dirty = TRUE
+		`, m.Version); dirtyErr != nil {
+			log.Printf("Warning: failed to mark migration %d dirty: %v", m.Version, dirtyErr)
+		}
+		return fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Name, err)
+	}
+
+	if markApplied {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO schema_migrations (version, dirty, applied_at) VALUES ($1, FALSE, now())
+			ON CONFLICT (version) DO UPDATE SET dirty = FALSE, applied_at = now()
+		`, m.Version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d as applied: %v", m.Version, err)
+		}
+	} else if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to remove migration %d from schema_migrations: %v", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %v", m.Version, err)
+	}
+	return nil
+}
+
+// Up applies every pending migration in ms.dir, in version order, stopping
+// at the first failure (which leaves that version marked dirty).
+func (ms *MigrationService) Up() error {
+	return ms.withLock(func(ctx context.Context, conn dbConn) error {
+		migrations, err := ms.loadMigrations()
+		if err != nil {
+			return err
+		}
+		applied, dirty, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if dirty[m.Version] {
+				return fmt.Errorf("migration %d is marked dirty; run `migrate force %d` after fixing it by hand", m.Version, m.Version)
+			}
+			if applied[m.Version] {
+				continue
+			}
+			log.Printf("Applying migration %04d_%s...", m.Version, m.Name)
+			if err := applyMigration(ctx, conn, m, m.Up, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverses the n most recently applied migrations, most recent first.
+func (ms *MigrationService) Down(n int) error {
+	return ms.withLock(func(ctx context.Context, conn dbConn) error {
+		migrations, err := ms.loadMigrations()
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[int64]Migration, len(migrations))
+		for _, m := range migrations {
+			byVersion[m.Version] = m
+		}
+
+		applied, dirty, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		var versions []int64
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for i := 0; i < n && i < len(versions); i++ {
+			version := versions[i]
+			if dirty[version] {
+				return fmt.Errorf("migration %d is marked dirty; run `migrate force %d` after fixing it by hand", version, version)
+			}
+			m, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("applied migration %d has no matching file in %s", version, ms.dir)
+			}
+			log.Printf("Reverting migration %04d_%s...", m.Version, m.Name)
+			if err := applyMigration(ctx, conn, m, m.Down, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Redo reverses and re-applies the single most recently applied migration.
+func (ms *MigrationService) Redo() error {
+	if err := ms.Down(1); err != nil {
+		return err
+	}
+	return ms.Up()
+}
+
+// Force clears the dirty flag on version without running its Up or Down
+// SQL, for use after manually fixing up a failed migration's effects.
+func (ms *MigrationService) Force(version int64) error {
+	if err := ensureSchemaMigrationsTable(context.Background(), ms.db); err != nil {
+		return err
+	}
+	_, err := ms.db.Exec(`
+		INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)
+		ON CONFLICT (version) DO UPDATE SET dirty = FALSE
+	`, version)
+	if err != nil {
+		return fmt.Errorf("failed to force-clear migration %d: %v", version, err)
+	}
+	return nil
+}
+
+// Status prints every migration in ms.dir alongside whether it's applied,
+// pending, or dirty.
+func (ms *MigrationService) Status() error {
+	migrations, err := ms.loadMigrations()
+	if err != nil {
+		return err
+	}
+	applied, dirty, err := appliedVersions(context.Background(), ms.db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		state := "pending"
+		if dirty[m.Version] {
+			state = "dirty"
+		} else if applied[m.Version] {
+			state = "applied"
+		}
+		fmt.Printf("%04d_%s: %s\n", m.Version, m.Name, state)
+	}
+	return nil
+}
+
+// Version returns the highest applied, non-dirty migration version, or 0 if
+// none have run yet.
+func (ms *MigrationService) Version() (int64, error) {
+	if err := ensureSchemaMigrationsTable(context.Background(), ms.db); err != nil {
+		return 0, err
+	}
+	var version int64
+	row := ms.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE NOT dirty`)
+	if err := row.Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read current migration version: %v", err)
+	}
+	return version, nil
+}
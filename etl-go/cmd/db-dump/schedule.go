@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/IngeniosoHacker/OLAP-Data-lakehouse/etl-go/internal/clock"
+	"github.com/IngeniosoHacker/OLAP-Data-lakehouse/etl-go/internal/jobrunner"
+)
+
+// scheduleJobConfig is one entry in a schedule YAML config's "jobs" list.
+//
+//	jobs:
+//	  - name: nightly-dump
+//	    type: dump
+//	    schedule: "0 2 * * *"
+//	    jitter: 5m
+//	    output: /backups/nightly
+//	    format: parquet
+//	    jobs: 4
+//	  - name: hourly-cdc-ingest
+//	    type: ingest
+//	    schedule: "@every 1h"
+//	    jitter: 30s
+//	    command: "ETL_SOURCE_TYPE=cdc CDC_TARGET_TABLE=orders etl-go"
+type scheduleJobConfig struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"` // "dump" or "ingest"
+	Schedule string `yaml:"schedule"`
+	Jitter   string `yaml:"jitter"`
+
+	// dump-type fields, matching the "dump" subcommand's flags.
+	Output    string `yaml:"output"`
+	Format    string `yaml:"format"`
+	Jobs      int    `yaml:"jobs"`
+	Compress  string `yaml:"compress"`
+	SplitSize string `yaml:"split_size"`
+	Dialect   string `yaml:"dialect"`
+	Timezone  string `yaml:"timezone"`
+
+	// ingest-type field: a shell command run on each firing, e.g. the
+	// main etl-go binary invoked with ETL_SOURCE_TYPE=cdc or =file.
+	Command string `yaml:"command"`
+}
+
+// scheduleConfig is a schedule YAML config's top-level shape.
+type scheduleConfig struct {
+	Jobs []scheduleJobConfig `yaml:"jobs"`
+}
+
+// loadScheduleConfig reads and parses a schedule YAML config from path.
+func loadScheduleConfig(path string) (*scheduleConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule config %q: %v", path, err)
+	}
+	var cfg scheduleConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule config %q: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// buildJobFunc turns a scheduleJobConfig into the closure jobrunner.JobRunner
+// invokes on each firing: a "dump" job reuses dumpService exactly like the
+// "dump" subcommand, and an "ingest" job shells out Command, since db-dump
+// doesn't own the file/SQL/CDC ingestion pipelines that live in the main
+// etl-go binary.
+func buildJobFunc(jc scheduleJobConfig, dumpService *DBDumpService) (jobrunner.JobFunc, error) {
+	switch jc.Type {
+	case "dump":
+		splitBytes, err := parseSplitSize(jc.SplitSize)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %v", jc.Name, err)
+		}
+		dialectName := jc.Dialect
+		if dialectName == "" {
+			dialectName = "postgres"
+		}
+		dialect, err := parseDialectName(dialectName)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %v", jc.Name, err)
+		}
+		timezone := jc.Timezone
+		if timezone == "" {
+			timezone = "UTC"
+		}
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: invalid timezone %q: %v", jc.Name, timezone, err)
+		}
+
+		opts := DumpOptions{Format: jc.Format, Jobs: jc.Jobs, Compress: jc.Compress, SplitSize: splitBytes}
+		output := jc.Output
+		return func(ctx context.Context) error {
+			// dumpService is shared across every job this scheduler runs;
+			// each firing dumps through its own copy that shares only the
+			// connection pool, so a concurrent firing of a
+			// differently-configured dump job can't race with this one's
+			// dialect/time zone settings.
+			jobService := &DBDumpService{db: dumpService.db}
+			jobService.SetDialect(dialect)
+			jobService.SetTimeZone(loc)
+			return jobService.DumpDatabaseWithOptions(output, opts)
+		}, nil
+	case "ingest":
+		if jc.Command == "" {
+			return nil, fmt.Errorf("job %q: type ingest requires command", jc.Name)
+		}
+		command := jc.Command
+		return func(ctx context.Context) error {
+			cmd := exec.CommandContext(ctx, "sh", "-c", command)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			return cmd.Run()
+		}, nil
+	default:
+		return nil, fmt.Errorf("job %q: unknown type %q (want dump or ingest)", jc.Name, jc.Type)
+	}
+}
+
+// runScheduleCommand handles "schedule [--config path] [--run-now name]":
+// it loads a schedule YAML config, registers each job with a
+// jobrunner.JobRunner backed by job_runs in the same database db-dump
+// dumps, and either runs one job immediately (--run-now) or starts the
+// scheduler and blocks.
+func runScheduleCommand(dbHost, dbName, dbUser, dbPassword string, args []string) {
+	fs := flag.NewFlagSet("schedule", flag.ExitOnError)
+	configPath := fs.String("config", "schedule.yaml", "path to the schedule YAML config")
+	runNow := fs.String("run-now", "", "job name to run immediately instead of starting the scheduler")
+	fs.Parse(args)
+
+	cfg, err := loadScheduleConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dumpService, err := NewDBDumpService(dbHost, dbName, dbUser, dbPassword)
+	if err != nil {
+		log.Fatalf("Failed to create dump service: %v", err)
+	}
+	defer dumpService.Close()
+
+	runner := jobrunner.NewJobRunner(dumpService.db, clock.SystemClock{})
+	for _, jc := range cfg.Jobs {
+		jitter, err := time.ParseDuration(jc.Jitter)
+		if err != nil && jc.Jitter != "" {
+			log.Fatalf("job %q: invalid jitter %q: %v", jc.Name, jc.Jitter, err)
+		}
+
+		run, err := buildJobFunc(jc, dumpService)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := runner.AddJob(jobrunner.JobSpec{Name: jc.Name, Schedule: jc.Schedule, Jitter: jitter, Run: run}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *runNow != "" {
+		if err := runner.RunNow(*runNow); err != nil {
+			log.Fatalf("Failed to run job %q: %v", *runNow, err)
+		}
+		return
+	}
+
+	if err := runner.Start(); err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
+	defer runner.Stop()
+
+	log.Println("Job scheduler started; press Ctrl+C to stop")
+	select {}
+}
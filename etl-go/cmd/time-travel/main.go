@@ -1,64 +1,258 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"time"
+
+	"github.com/IngeniosoHacker/OLAP-Data-lakehouse/etl-go/internal/clock"
+	"github.com/IngeniosoHacker/OLAP-Data-lakehouse/etl-go/internal/snapshot"
 )
 
-// TimeTravelService handles time manipulation for testing
-type TimeTravelService struct{}
+// asOfTimestampLayout is the "YYYY-MM-DD HH:MM:SS" format the query/diff/
+// restore-table subcommands parse their --as-of/--from/--to/--at flags
+// with, matching SetTime's existing -set flag format.
+const asOfTimestampLayout = "2006-01-02 15:04:05"
+
+// connectSnapshotStore opens a snapshot.SnapshotStore using the same
+// DB_HOST/DB_NAME/DB_USER/DB_PASSWORD environment variables db-dump and the
+// main ETL binary use.
+func connectSnapshotStore() *snapshot.SnapshotStore {
+	dbHost := os.Getenv("DB_HOST")
+	if dbHost == "" {
+		dbHost = "localhost"
+	}
+	dbName := os.Getenv("DB_NAME")
+	dbUser := os.Getenv("DB_USER")
+	dbPassword := os.Getenv("DB_PASSWORD")
+	if dbName == "" || dbUser == "" {
+		log.Fatal("DB_NAME and DB_USER environment variables must be set")
+	}
+
+	store, err := snapshot.NewSnapshotStore(dbHost, dbName, dbUser, dbPassword)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	return store
+}
+
+// runQueryCommand implements `timetravel query --table <name> --as-of
+// 'YYYY-MM-DD HH:MM:SS'`: it prints, one JSON object per line, every row
+// of table as it stood at the given instant.
+func runQueryCommand(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	table := fs.String("table", "", "table to query")
+	asOf := fs.String("as-of", "", "timestamp to query as of, format: "+asOfTimestampLayout)
+	fs.Parse(args)
+
+	if *table == "" || *asOf == "" {
+		log.Fatalf("usage: timetravel query --table <name> --as-of '%s'", asOfTimestampLayout)
+	}
+	asOfTime, err := time.Parse(asOfTimestampLayout, *asOf)
+	if err != nil {
+		log.Fatalf("invalid --as-of timestamp: %v", err)
+	}
+
+	store := connectSnapshotStore()
+	defer store.Close()
+
+	rows, err := store.SelectAsOf(*table, asOfTime)
+	if err != nil {
+		log.Fatalf("timetravel query failed: %v", err)
+	}
+	for _, row := range rows {
+		rowJSON, err := json.Marshal(row)
+		if err != nil {
+			log.Fatalf("failed to marshal row: %v", err)
+		}
+		fmt.Println(string(rowJSON))
+	}
+}
+
+// runDiffCommand implements `timetravel diff --table <name> --from
+// 'YYYY-MM-DD HH:MM:SS' --to 'YYYY-MM-DD HH:MM:SS'`: it prints the pk and
+// before/after row for every row that changed between the two instants.
+func runDiffCommand(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	table := fs.String("table", "", "table to diff")
+	from := fs.String("from", "", "start timestamp, format: "+asOfTimestampLayout)
+	to := fs.String("to", "", "end timestamp, format: "+asOfTimestampLayout)
+	fs.Parse(args)
+
+	if *table == "" || *from == "" || *to == "" {
+		log.Fatalf("usage: timetravel diff --table <name> --from '%s' --to '%s'", asOfTimestampLayout, asOfTimestampLayout)
+	}
+	fromTime, err := time.Parse(asOfTimestampLayout, *from)
+	if err != nil {
+		log.Fatalf("invalid --from timestamp: %v", err)
+	}
+	toTime, err := time.Parse(asOfTimestampLayout, *to)
+	if err != nil {
+		log.Fatalf("invalid --to timestamp: %v", err)
+	}
+
+	store := connectSnapshotStore()
+	defer store.Close()
+
+	diff, err := store.Diff(*table, fromTime, toTime)
+	if err != nil {
+		log.Fatalf("timetravel diff failed: %v", err)
+	}
+	for pk, change := range diff {
+		before, _ := json.Marshal(change[0])
+		after, _ := json.Marshal(change[1])
+		fmt.Printf("%s: %s -> %s\n", pk, before, after)
+	}
+}
+
+// runRestoreTableCommand implements `timetravel restore-table --table
+// <name> --as <new-name> --at 'YYYY-MM-DD HH:MM:SS'`: it reconstructs
+// table as it stood at the given instant into a new table named --as,
+// for recovering a dropped or truncated table.
+func runRestoreTableCommand(args []string) {
+	fs := flag.NewFlagSet("restore-table", flag.ExitOnError)
+	table := fs.String("table", "", "table to restore")
+	as := fs.String("as", "", "name of the new table to restore into")
+	at := fs.String("at", "", "timestamp to restore as of, format: "+asOfTimestampLayout)
+	fs.Parse(args)
+
+	if *table == "" || *as == "" || *at == "" {
+		log.Fatalf("usage: timetravel restore-table --table <name> --as <new-name> --at '%s'", asOfTimestampLayout)
+	}
+	atTime, err := time.Parse(asOfTimestampLayout, *at)
+	if err != nil {
+		log.Fatalf("invalid --at timestamp: %v", err)
+	}
+
+	store := connectSnapshotStore()
+	defer store.Close()
+
+	count, err := store.RestoreTable(*table, *as, atTime)
+	if err != nil {
+		log.Fatalf("timetravel restore-table failed: %v", err)
+	}
+	log.Printf("Restored %d row(s) from %s as of %s into %s", count, *table, atTime, *as)
+}
+
+// runCompactCommand implements `timetravel compact --table <name>
+// --retention 720h [--watch --interval 1h]`: it prunes closed history rows
+// older than --retention, once or (with --watch) on a repeating ticker.
+func runCompactCommand(args []string) {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	table := fs.String("table", "", "table whose history to compact")
+	retention := fs.Duration("retention", 30*24*time.Hour, "how long to keep closed history rows")
+	watch := fs.Bool("watch", false, "keep running, pruning every --interval instead of exiting after one pass")
+	interval := fs.Duration("interval", time.Hour, "how often to prune when --watch is set")
+	fs.Parse(args)
+
+	if *table == "" {
+		log.Fatal("usage: timetravel compact --table <name> --retention 720h [--watch --interval 1h]")
+	}
+
+	store := connectSnapshotStore()
+	defer store.Close()
+
+	prune := func() {
+		pruned, err := store.PruneHistory(*table, *retention)
+		if err != nil {
+			log.Printf("Warning: compaction of %s failed: %v", *table, err)
+			return
+		}
+		log.Printf("Compacted %s: pruned %d history row(s) older than %s", *table, pruned, *retention)
+	}
+
+	prune()
+	if !*watch {
+		return
+	}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		prune()
+	}
+}
+
+// TimeTravelService simulates time for testing by wrapping a
+// clock.MockClock: SetTime/AdvanceTime/GoBackTime move the mock clock
+// itself, rather than just printing what a real implementation would do,
+// so anything built against clock.Clock (windowed aggregates, SCD-2
+// effective dates, the job scheduler's catch-up/jitter logic) observes the
+// change the moment it queries Now().
+type TimeTravelService struct {
+	mock *clock.MockClock
+}
+
+// NewTimeTravelService returns a TimeTravelService whose mock clock starts
+// at the real current time.
+func NewTimeTravelService() *TimeTravelService {
+	return &TimeTravelService{mock: clock.NewMockClock(time.Now())}
+}
 
-// SetTime sets a specific time for testing purposes
+// Clock returns the underlying clock.Clock, for wiring into code that
+// should observe this service's simulated time.
+func (tts *TimeTravelService) Clock() clock.Clock {
+	return tts.mock
+}
+
+// SetTime sets the mock clock to a specific date and time.
 func (tts *TimeTravelService) SetTime(year, month, day, hour, min, sec int) error {
-	// In a real implementation, this might interact with a time mocking library
-	// or set environment variables that influence the application's perception of time
-	// For now, we'll just print the time that would be set
-	
-	mockTime := time.Date(year, int(month), day, hour, min, sec, 0, time.UTC)
-	fmt.Printf("Time travel simulation: Time set to %s\n", mockTime.Format("2006-01-02 15:04:05 UTC"))
-	
-	// In a real implementation, you'd set this time in a configuration file
-	// or environment variable that your application can read
+	newTime := time.Date(year, time.Month(month), day, hour, min, sec, 0, time.UTC)
+	tts.mock.Set(newTime)
+	fmt.Printf("Time travel simulation: Time set to %s\n", newTime.Format("2006-01-02 15:04:05 UTC"))
 	return nil
 }
 
-// AdvanceTime advances the time by a specified duration
+// AdvanceTime advances the mock clock by duration.
 func (tts *TimeTravelService) AdvanceTime(duration string) error {
 	durationParsed, err := time.ParseDuration(duration)
 	if err != nil {
 		return fmt.Errorf("invalid duration format: %v", err)
 	}
-	
-	currentTime := time.Now()
-	newTime := currentTime.Add(durationParsed)
-	fmt.Printf("Time travel simulation: Advanced by %s, now at %s\n", duration, newTime.Format("2006-01-02 15:04:05 UTC"))
-	
+
+	tts.mock.Advance(durationParsed)
+	fmt.Printf("Time travel simulation: Advanced by %s, now at %s\n", duration, tts.mock.Now().Format("2006-01-02 15:04:05 UTC"))
 	return nil
 }
 
-// GoBackTime moves the time back by a specified duration
+// GoBackTime moves the mock clock back by duration.
 func (tts *TimeTravelService) GoBackTime(duration string) error {
 	durationParsed, err := time.ParseDuration(duration)
 	if err != nil {
 		return fmt.Errorf("invalid duration format: %v", err)
 	}
-	
-	currentTime := time.Now()
-	newTime := currentTime.Add(-durationParsed)
-	fmt.Printf("Time travel simulation: Went back by %s, now at %s\n", duration, newTime.Format("2006-01-02 15:04:05 UTC"))
-	
+
+	tts.mock.Advance(-durationParsed)
+	fmt.Printf("Time travel simulation: Went back by %s, now at %s\n", duration, tts.mock.Now().Format("2006-01-02 15:04:05 UTC"))
 	return nil
 }
 
-// ResetTime resets to current real time
+// ResetTime resets the mock clock to the current real time.
 func (tts *TimeTravelService) ResetTime() {
-	fmt.Printf("Time travel simulation: Reset to current real time: %s\n", time.Now().Format("2006-01-02 15:04:05 UTC"))
+	tts.mock.Set(time.Now())
+	fmt.Printf("Time travel simulation: Reset to current real time: %s\n", tts.mock.Now().Format("2006-01-02 15:04:05 UTC"))
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			runQueryCommand(os.Args[2:])
+			return
+		case "diff":
+			runDiffCommand(os.Args[2:])
+			return
+		case "restore-table":
+			runRestoreTableCommand(os.Args[2:])
+			return
+		case "compact":
+			runCompactCommand(os.Args[2:])
+			return
+		}
+	}
+
 	var (
 		setTimeFlag = flag.String("set", "", "Set time in format: YYYY-MM-DD HH:MM:SS")
 		advanceFlag = flag.String("advance", "", "Advance time by duration (e.g., 1h, 30m)")
@@ -77,10 +271,16 @@ func main() {
 		fmt.Println("  -back DURATION               Go back in time by specified duration")
 		fmt.Println("  -reset                       Reset to current real time")
 		fmt.Println("  -help                        Show this help")
+		fmt.Println()
+		fmt.Println("Real as-of queries backed by a MVCC history table (see SnapshotStore):")
+		fmt.Println("  query --table T --as-of TS             print T's rows as of TS")
+		fmt.Println("  diff --table T --from TS --to TS        print what changed in T between two instants")
+		fmt.Println("  restore-table --table T --as NEW --at TS  reconstruct T as of TS into table NEW")
+		fmt.Println("  compact --table T --retention DUR       prune T's history older than DUR")
 		os.Exit(0)
 	}
 
-	tts := &TimeTravelService{}
+	tts := NewTimeTravelService()
 
 	if *setTimeFlag != "" {
 		var year, month, day, hour, min, sec int
@@ -105,4 +305,4 @@ func main() {
 		// Default: show current time
 		fmt.Printf("Current time: %s\n", time.Now().Format("2006-01-02 15:04:05 UTC"))
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,233 @@
+// Package apiserver exposes a small HTTP API that lets external systems
+// (dashboards, alerting) trigger ad-hoc report deliveries without editing the
+// YAML email config, modeled on listmonk's POST /api/tx/external endpoint.
+package apiserver
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/IngeniosoHacker/OLAP-Data-lakehouse/etl-go/internal/services"
+)
+
+// defaultMaxRequestBytes bounds a POST /api/tx body when
+// Settings.APIMaxRequestBytes isn't configured.
+const defaultMaxRequestBytes = 10 << 20 // 10 MiB
+
+// Server exposes the transactional email API over HTTP.
+type Server struct {
+	config       *services.EmailConfig
+	emailService *services.EmailService
+}
+
+// NewServer creates a Server backed by config and emailService.
+func NewServer(config *services.EmailConfig, emailService *services.EmailService) *Server {
+	return &Server{config: config, emailService: emailService}
+}
+
+// Handler returns the http.Handler to mount, routing POST /api/tx to
+// handleSendTx.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tx", s.handleSendTx)
+	return mux
+}
+
+// txRecipient is the JSON shape of a recipient in a transactional send
+// request; it mirrors services.Recipient but omits ReportTypes, which isn't
+// meaningful for a one-off send.
+type txRecipient struct {
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Department string `json:"department"`
+}
+
+// txAttachment is the JSON shape of a base64-encoded attachment.
+type txAttachment struct {
+	Filename      string `json:"filename"`
+	ContentBase64 string `json:"content_base64"`
+	ContentType   string `json:"content_type"`
+}
+
+// txRequest is the decoded body of POST /api/tx, whether it arrived as a
+// plain JSON request or as the "data" field of a multipart/form-data request.
+type txRequest struct {
+	Recipient    txRecipient            `json:"recipient"`
+	ReportType   string                 `json:"report_type"`
+	TemplateVars map[string]interface{} `json:"template_vars"`
+	Attachments  []txAttachment         `json:"attachments"`
+}
+
+// apiError is the structured JSON body returned on any failure.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: message})
+}
+
+// handleSendTx implements POST /api/tx: it authenticates the request, decodes
+// either a JSON or multipart/form-data body, and dispatches the report
+// through EmailService. On success it responds 202 Accepted.
+func (s *Server) handleSendTx(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !s.authorize(r) {
+		writeJSONError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+
+	maxBytes := s.config.Settings.APIMaxRequestBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxRequestBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	req, attachments, err := s.decodeRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Recipient.Email == "" {
+		writeJSONError(w, http.StatusBadRequest, "recipient.email is required")
+		return
+	}
+	if req.ReportType == "" {
+		writeJSONError(w, http.StatusBadRequest, "report_type is required")
+		return
+	}
+
+	recipient := services.Recipient{
+		Name:       req.Recipient.Name,
+		Email:      req.Recipient.Email,
+		Department: req.Recipient.Department,
+	}
+
+	if err := s.emailService.SendTransactionalReport(req.ReportType, recipient, req.TemplateVars, attachments); err != nil {
+		writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to send report: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "sent"})
+}
+
+// authorize checks the Authorization: Bearer header against
+// Settings.APIBearerToken. An empty configured token disables auth, which is
+// convenient for local/dry-run setups but should never be used in production.
+func (s *Server) authorize(r *http.Request) bool {
+	token := s.config.Settings.APIBearerToken
+	if token == "" {
+		return true
+	}
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// decodeRequest parses either a plain JSON body or a multipart/form-data body
+// (JSON in a "data" field, files as additional parts) into a txRequest and
+// its decoded attachments.
+func (s *Server) decodeRequest(r *http.Request) (txRequest, []services.Attachment, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return txRequest{}, nil, fmt.Errorf("invalid Content-Type: %v", err)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return s.decodeMultipart(r, params["boundary"])
+	}
+
+	var req txRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return txRequest{}, nil, fmt.Errorf("invalid JSON body: %v", err)
+	}
+
+	attachments, err := decodeBase64Attachments(req.Attachments)
+	if err != nil {
+		return txRequest{}, nil, err
+	}
+	return req, attachments, nil
+}
+
+// decodeMultipart parses a multipart/form-data body whose "data" field holds
+// the JSON request and whose remaining parts are file attachments.
+func (s *Server) decodeMultipart(r *http.Request, boundary string) (txRequest, []services.Attachment, error) {
+	if boundary == "" {
+		return txRequest{}, nil, fmt.Errorf("multipart request is missing a boundary")
+	}
+
+	var req txRequest
+	var attachments []services.Attachment
+
+	reader := multipart.NewReader(r.Body, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return txRequest{}, nil, fmt.Errorf("failed to read multipart body: %v", err)
+		}
+
+		if part.FormName() == "data" {
+			if err := json.NewDecoder(part).Decode(&req); err != nil {
+				return txRequest{}, nil, fmt.Errorf("invalid JSON in data field: %v", err)
+			}
+			continue
+		}
+
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return txRequest{}, nil, fmt.Errorf("failed to read attachment part %q: %v", part.FormName(), err)
+		}
+
+		contentType := part.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		attachments = append(attachments, services.Attachment{
+			Filename:    part.FileName(),
+			ContentType: contentType,
+			Data:        data,
+		})
+	}
+
+	return req, attachments, nil
+}
+
+// decodeBase64Attachments decodes the content_base64 payload of each JSON
+// attachment into a services.Attachment.
+func decodeBase64Attachments(txAtts []txAttachment) ([]services.Attachment, error) {
+	if len(txAtts) == 0 {
+		return nil, nil
+	}
+
+	attachments := make([]services.Attachment, 0, len(txAtts))
+	for _, txAtt := range txAtts {
+		data, err := base64.StdEncoding.DecodeString(txAtt.ContentBase64)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %q has invalid base64 content: %v", txAtt.Filename, err)
+		}
+		attachments = append(attachments, services.Attachment{
+			Filename:    txAtt.Filename,
+			ContentType: txAtt.ContentType,
+			Data:        data,
+		})
+	}
+	return attachments, nil
+}
@@ -0,0 +1,126 @@
+package cdc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// positionRecord is FilePositionStore's on-disk representation for one
+// stream.
+type positionRecord struct {
+	StreamID  string    `json:"stream_id"`
+	Position  string    `json:"position"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FilePositionStore persists one JSON sidecar file per stream, named
+// cdc-<streamID>.position in Dir. It's the default PositionStore, so a
+// single-node deployment can resume a CDC stream without a separate
+// metadata database.
+type FilePositionStore struct {
+	Dir string
+}
+
+func (s FilePositionStore) path(streamID string) string {
+	dir := s.Dir
+	if dir == "" {
+		dir = "."
+	}
+	return fmt.Sprintf("%s/cdc-%s.position", dir, streamID)
+}
+
+// Load reads the sidecar file for streamID, if any.
+func (s FilePositionStore) Load(ctx context.Context, streamID string) (string, bool, error) {
+	data, err := os.ReadFile(s.path(streamID))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read CDC position for %s: %v", streamID, err)
+	}
+
+	var rec positionRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return "", false, fmt.Errorf("failed to parse CDC position for %s: %v", streamID, err)
+	}
+	return rec.Position, true, nil
+}
+
+// Save overwrites the sidecar file for streamID with position.
+func (s FilePositionStore) Save(ctx context.Context, streamID, position string) error {
+	rec := positionRecord{StreamID: streamID, Position: position, UpdatedAt: time.Now()}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal CDC position for %s: %v", streamID, err)
+	}
+	if err := os.WriteFile(s.path(streamID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write CDC position for %s: %v", streamID, err)
+	}
+	return nil
+}
+
+// PostgresPositionStore persists stream positions in the
+// cdc_stream_positions table, so a CDC consumer that fails over to another
+// node can resume from the same position via the target database.
+type PostgresPositionStore struct {
+	db *sql.DB
+}
+
+// NewPostgresPositionStore creates a PostgresPositionStore backed by db.
+func NewPostgresPositionStore(db *sql.DB) *PostgresPositionStore {
+	return &PostgresPositionStore{db: db}
+}
+
+func (s *PostgresPositionStore) ensureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS cdc_stream_positions (
+			stream_id  TEXT PRIMARY KEY,
+			position   TEXT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create cdc_stream_positions table: %v", err)
+	}
+	return nil
+}
+
+// Load returns the saved position for streamID, if any.
+func (s *PostgresPositionStore) Load(ctx context.Context, streamID string) (string, bool, error) {
+	if err := s.ensureTable(ctx); err != nil {
+		return "", false, err
+	}
+
+	var position string
+	err := s.db.QueryRowContext(ctx, `SELECT position FROM cdc_stream_positions WHERE stream_id = $1`, streamID).Scan(&position)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load CDC position for %s: %v", streamID, err)
+	}
+	return position, true, nil
+}
+
+// Save upserts position for streamID.
+func (s *PostgresPositionStore) Save(ctx context.Context, streamID, position string) error {
+	if err := s.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO cdc_stream_positions (stream_id, position, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (stream_id) DO UPDATE SET
+			position   = EXCLUDED.position,
+			updated_at = EXCLUDED.updated_at
+	`, streamID, position)
+	if err != nil {
+		return fmt.Errorf("failed to save CDC position for %s: %v", streamID, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package checkpoint
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	sourceID := filepath.Join(t.TempDir(), "orders.csv")
+	s := FileStore{}
+
+	if _, ok, err := s.Load(context.Background(), sourceID); err != nil || ok {
+		t.Fatalf("Load on a fresh source = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	want := &Checkpoint{
+		SourceID:      sourceID,
+		ContentHash:   "deadbeef",
+		RowsProcessed: 1234,
+		BatchID:       3,
+	}
+	if err := s.Save(context.Background(), want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, ok, err := s.Load(context.Background(), sourceID)
+	if err != nil || !ok {
+		t.Fatalf("Load after Save = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if got.ContentHash != want.ContentHash || got.RowsProcessed != want.RowsProcessed || got.BatchID != want.BatchID {
+		t.Errorf("Load = %+v, want fields matching %+v", got, want)
+	}
+	if got.UpdatedAt.IsZero() {
+		t.Error("Save should have stamped UpdatedAt")
+	}
+
+	// Saving again with new progress overwrites rather than appends.
+	want.RowsProcessed = 5678
+	if err := s.Save(context.Background(), want); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+	got, _, err = s.Load(context.Background(), sourceID)
+	if err != nil {
+		t.Fatalf("Load after second Save: %v", err)
+	}
+	if got.RowsProcessed != 5678 {
+		t.Errorf("RowsProcessed = %d after second Save, want 5678 (overwrite, not append)", got.RowsProcessed)
+	}
+}
+
+func TestPostgresStoreSaveUpsertsOnConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS etl_checkpoints").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO etl_checkpoints .* ON CONFLICT \\(source_id, content_hash\\) DO UPDATE SET").
+		WithArgs("orders.csv", "hash-1", int64(100), 0, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	store := NewPostgresStore(db)
+	cp := &Checkpoint{SourceID: "orders.csv", ContentHash: "hash-1", RowsProcessed: 100}
+	if err := store.Save(context.Background(), cp); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if cp.UpdatedAt.IsZero() {
+		t.Error("Save should stamp UpdatedAt before upserting")
+	}
+
+	// A second Save with the same (source_id, content_hash) takes the
+	// ON CONFLICT branch rather than failing on the primary key.
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS etl_checkpoints").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO etl_checkpoints .* ON CONFLICT \\(source_id, content_hash\\) DO UPDATE SET").
+		WithArgs("orders.csv", "hash-1", int64(250), 0, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	cp.RowsProcessed = 250
+	if err := store.Save(context.Background(), cp); err != nil {
+		t.Fatalf("second Save (conflict path): %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresStoreLoadReturnsMostRecentByUpdatedAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS etl_checkpoints").WillReturnResult(sqlmock.NewResult(0, 0))
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"source_id", "content_hash", "rows_processed", "batch_id", "updated_at"}).
+		AddRow("orders.csv", "hash-2", int64(250), 1, now)
+	mock.ExpectQuery("SELECT source_id, content_hash, rows_processed, batch_id, updated_at FROM etl_checkpoints WHERE source_id = \\$1 ORDER BY updated_at DESC LIMIT 1").
+		WithArgs("orders.csv").
+		WillReturnRows(rows)
+
+	store := NewPostgresStore(db)
+	got, ok, err := store.Load(context.Background(), "orders.csv")
+	if err != nil || !ok {
+		t.Fatalf("Load = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if got.ContentHash != "hash-2" || got.RowsProcessed != 250 {
+		t.Errorf("Load = %+v, want the most recently updated row", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
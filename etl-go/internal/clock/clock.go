@@ -0,0 +1,177 @@
+// Package clock abstracts time so time-sensitive transforms (windowed
+// aggregates, SCD-2 effective dates) and the job scheduler can be driven
+// deterministically in tests by advancing a MockClock instead of sleeping
+// on the wall clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the subset of the time package callers need. Production code
+// takes a Clock instead of calling time.Now/time.Since/time.After/
+// time.NewTicker directly, so it can be swapped for a MockClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Since returns the time elapsed since t, i.e. Now().Sub(t).
+	Since(t time.Time) time.Duration
+	// After returns a channel that receives the current time once d has
+	// elapsed.
+	After(d time.Duration) <-chan time.Time
+	// NewTicker returns a Ticker that sends the current time on its
+	// channel every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the subset of *time.Ticker that Clock.NewTicker returns, so
+// MockTicker can stand in for *time.Ticker in tests.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is a Clock backed by the real wall clock, the implementation
+// used outside of tests.
+type SystemClock struct{}
+
+// Now returns time.Now().
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// Since returns time.Since(t).
+func (SystemClock) Since(t time.Time) time.Duration { return time.Since(t) }
+
+// After returns time.After(d).
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// NewTicker wraps time.NewTicker(d).
+func (SystemClock) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+// systemTicker adapts *time.Ticker to the Ticker interface.
+type systemTicker struct{ t *time.Ticker }
+
+func (s systemTicker) C() <-chan time.Time { return s.t.C }
+func (s systemTicker) Stop()               { s.t.Stop() }
+
+// MockClock is a Clock whose Now() only moves when Set or Advance is
+// called, for deterministically testing windowed aggregates, SCD-2
+// effective-dating, and scheduler catch-up/jitter logic without sleeping.
+type MockClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []mockWaiter
+}
+
+// mockWaiter is one pending After call: it fires once the clock reaches
+// (or passes) at.
+type mockWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewMockClock returns a MockClock starting at start.
+func NewMockClock(start time.Time) *MockClock {
+	return &MockClock{now: start}
+}
+
+// Now returns the mock's current time.
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns c.Now().Sub(t).
+func (c *MockClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Set moves the mock's time to t, firing any pending After/ticker waiters
+// whose deadline t has reached or passed.
+func (c *MockClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	remaining := c.waiters[:0]
+	for _, w := range c.waiters {
+		if !t.Before(w.at) {
+			w.ch <- t
+		} else {
+			remaining = append(remaining, w)
+		}
+	}
+	c.waiters = remaining
+	c.mu.Unlock()
+}
+
+// Advance moves the mock's time forward by d. A negative d goes back in
+// time, matching GoBackTime's historical behavior.
+func (c *MockClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// After returns a channel that receives the mock's time once it reaches
+// Now()+d, which only happens via Set/Advance.
+func (c *MockClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.mu.Lock()
+	at := c.now.Add(d)
+	if !at.After(c.now) {
+		c.mu.Unlock()
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, mockWaiter{at: at, ch: ch})
+	c.mu.Unlock()
+	return ch
+}
+
+// NewTicker returns a Ticker backed by the mock clock: its channel
+// receives a tick every time Advance/Set moves the mock's time past the
+// next d boundary.
+func (c *MockClock) NewTicker(d time.Duration) Ticker {
+	t := &mockTicker{clock: c, interval: d, ch: make(chan time.Time, 1)}
+	t.scheduleNext()
+	return t
+}
+
+// mockTicker is the MockClock-backed Ticker implementation. Each tick
+// re-arms itself by registering a fresh After wait for the next interval.
+type mockTicker struct {
+	clock    *MockClock
+	interval time.Duration
+	ch       chan time.Time
+	mu       sync.Mutex
+	stopped  bool
+}
+
+func (t *mockTicker) scheduleNext() {
+	next := t.clock.After(t.interval)
+	go func() {
+		tm, ok := <-next
+		if !ok {
+			return
+		}
+		t.mu.Lock()
+		stopped := t.stopped
+		t.mu.Unlock()
+		if stopped {
+			return
+		}
+		// Re-arm before delivering the tick, so an Advance made by
+		// whoever receives it always sees the next wait registered.
+		t.scheduleNext()
+		select {
+		case t.ch <- tm:
+		default:
+		}
+	}()
+}
+
+func (t *mockTicker) C() <-chan time.Time { return t.ch }
+
+func (t *mockTicker) Stop() {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+}
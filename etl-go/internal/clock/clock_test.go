@@ -0,0 +1,99 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMockClockNowAndSince(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMockClock(start)
+
+	if got := c.Now(); !got.Equal(start) {
+		t.Fatalf("Now() = %v, want %v", got, start)
+	}
+
+	c.Advance(5 * time.Second)
+	if got, want := c.Since(start), 5*time.Second; got != want {
+		t.Fatalf("Since(start) = %v, want %v", got, want)
+	}
+}
+
+func TestMockClockAfterFiresOnAdvance(t *testing.T) {
+	c := NewMockClock(time.Unix(0, 0))
+	ch := c.After(time.Second)
+
+	select {
+	case <-ch:
+		t.Fatal("After fired before the clock advanced")
+	default:
+	}
+
+	c.Advance(time.Second)
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("After did not fire once the clock reached its deadline")
+	}
+}
+
+func TestMockClockAfterNonPositiveDurationFiresImmediately(t *testing.T) {
+	c := NewMockClock(time.Unix(0, 0))
+	ch := c.After(0)
+
+	select {
+	case <-ch:
+	default:
+		t.Fatal("After(0) should fire without waiting for Set/Advance")
+	}
+}
+
+func TestMockClockSetFiresPastDeadlines(t *testing.T) {
+	c := NewMockClock(time.Unix(0, 0))
+	ch := c.After(time.Minute)
+
+	c.Set(time.Unix(0, 0).Add(time.Hour))
+
+	select {
+	case <-ch:
+	default:
+		t.Fatal("Set past a waiter's deadline should fire it")
+	}
+}
+
+func TestMockTickerRearms(t *testing.T) {
+	c := NewMockClock(time.Unix(0, 0))
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 0; i < 3; i++ {
+		c.Advance(time.Second)
+		select {
+		case <-ticker.C():
+		case <-time.After(time.Second):
+			t.Fatalf("tick %d: ticker did not re-arm after the previous tick", i)
+		}
+	}
+}
+
+func TestMockTickerStopSuppressesFurtherTicks(t *testing.T) {
+	c := NewMockClock(time.Unix(0, 0))
+	ticker := c.NewTicker(time.Second)
+
+	c.Advance(time.Second)
+	select {
+	case <-ticker.C():
+	case <-time.After(time.Second):
+		t.Fatal("expected an initial tick before Stop")
+	}
+
+	ticker.Stop()
+	c.Advance(5 * time.Second)
+
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker delivered a tick after Stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
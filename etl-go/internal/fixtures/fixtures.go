@@ -0,0 +1,377 @@
+// Package fixtures loads deterministic test data into PostgreSQL before an
+// integration test runs, modelled on testfixtures.v2: a directory of
+// YAML/JSON files named after tables, each a map of named rows, is
+// truncated and reloaded by Load. Foreign keys are handled the same way
+// cmd/db-dump's single-file dumps already do (see dumpHeader/dumpFooter):
+// SET session_replication_role = replica suspends trigger/FK enforcement
+// for the load, on top of SET CONSTRAINTS ALL DEFERRED for any deferrable
+// constraints, so fixture files can be loaded in any order regardless of
+// their foreign key dependencies. Every sequence backing a loaded table is
+// then reset via pg_get_serial_sequence/setval so subsequent inserts
+// continue past the fixture rows' IDs instead of colliding with them.
+package fixtures
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v2"
+)
+
+// Loader loads fixture files from Dir into DB's tables. Construct one with
+// New.
+type Loader struct {
+	db  *sql.DB
+	dir string
+}
+
+// Option configures a Loader constructed by New.
+type Option func(*Loader)
+
+// Directory sets the directory fixture files are read from. If omitted,
+// New defaults to "testdata".
+func Directory(dir string) Option {
+	return func(l *Loader) { l.dir = dir }
+}
+
+// New creates a Loader that loads fixture files into db's database.
+func New(db *sql.DB, opts ...Option) *Loader {
+	l := &Loader{db: db, dir: "testdata"}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// table holds one fixture file's parsed rows. rowOrder is sorted by row
+// name rather than preserving file order, so Load's insert order (and
+// hence any sequence-collision edge cases) stays identical across runs
+// regardless of how the fixture file happens to be formatted.
+type table struct {
+	name     string
+	rowOrder []string
+	rows     map[string]map[string]interface{}
+}
+
+// Load truncates every table with a fixture file in l.dir, then inserts
+// that file's rows, all inside one transaction that's rolled back if any
+// step fails. {{now}}, {{uuid}}, and {{table.row.field}} templates (see
+// resolveTemplates) are expanded before any row is inserted, so cross-row
+// references always see the same generated value the referenced field
+// does.
+func (l *Loader) Load() error {
+	tables, err := l.readDir()
+	if err != nil {
+		return err
+	}
+	if err := resolveTemplates(tables); err != nil {
+		return err
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin fixture load transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SET CONSTRAINTS ALL DEFERRED"); err != nil {
+		return fmt.Errorf("failed to defer constraints: %v", err)
+	}
+	if _, err := tx.Exec("SET session_replication_role = replica"); err != nil {
+		return fmt.Errorf("failed to disable triggers for fixture load: %v", err)
+	}
+
+	for _, t := range tables {
+		if _, err := tx.Exec(fmt.Sprintf("TRUNCATE TABLE %s CASCADE", quoteIdent(t.name))); err != nil {
+			return fmt.Errorf("failed to truncate %s: %v", t.name, err)
+		}
+	}
+
+	for _, t := range tables {
+		for _, rowName := range t.rowOrder {
+			if err := insertRow(tx, t.name, t.rows[rowName]); err != nil {
+				return fmt.Errorf("failed to insert %s.%s: %v", t.name, rowName, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec("SET session_replication_role = DEFAULT"); err != nil {
+		return fmt.Errorf("failed to re-enable triggers after fixture load: %v", err)
+	}
+
+	for _, t := range tables {
+		if err := resetSequences(tx, t.name); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fixture load: %v", err)
+	}
+	return nil
+}
+
+// readDir parses every *.yaml, *.yml, and *.json file directly in l.dir
+// into a table named after the file (minus extension).
+func (l *Loader) readDir() ([]*table, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture directory %s: %v", l.dir, err)
+	}
+
+	var tables []*table
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(entry.Name())
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(l.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture file %s: %v", entry.Name(), err)
+		}
+
+		raw, err := parseFixtureFile(ext, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fixture file %s: %v", entry.Name(), err)
+		}
+
+		rows := make(map[string]map[string]interface{}, len(raw))
+		rowOrder := make([]string, 0, len(raw))
+		for rowName, fields := range raw {
+			fieldMap, ok := fields.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("fixture file %s: row %q is not a map of fields", entry.Name(), rowName)
+			}
+			rows[rowName] = fieldMap
+			rowOrder = append(rowOrder, rowName)
+		}
+		sort.Strings(rowOrder)
+
+		tables = append(tables, &table{
+			name:     strings.TrimSuffix(entry.Name(), ext),
+			rowOrder: rowOrder,
+			rows:     rows,
+		})
+	}
+
+	sort.Slice(tables, func(i, j int) bool { return tables[i].name < tables[j].name })
+	return tables, nil
+}
+
+// parseFixtureFile unmarshals a fixture file's top-level row-name -> field
+// map. YAML unmarshals nested maps as map[interface{}]interface{}, which
+// normalizeYAMLValue converts to the map[string]interface{} shape the rest
+// of this package expects (the same shape json.Unmarshal already produces).
+func parseFixtureFile(ext string, data []byte) (map[string]interface{}, error) {
+	if ext == ".json" {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return normalizeYAMLMap(raw), nil
+}
+
+func normalizeYAMLMap(m map[interface{}]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[fmt.Sprintf("%v", k)] = normalizeYAMLValue(v)
+	}
+	return out
+}
+
+func normalizeYAMLValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		return normalizeYAMLMap(vv)
+	case []interface{}:
+		out := make([]interface{}, len(vv))
+		for i, item := range vv {
+			out[i] = normalizeYAMLValue(item)
+		}
+		return out
+	default:
+		return vv
+	}
+}
+
+// templateRe matches a single {{...}} template placeholder.
+var templateRe = regexp.MustCompile(`\{\{[^}]*\}\}`)
+
+// refRe matches a {{table.row.field}} cross-reference, once its {{}}
+// delimiters and surrounding whitespace have been stripped.
+var refRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\.[A-Za-z_][A-Za-z0-9_]*\.[A-Za-z_][A-Za-z0-9_]*$`)
+
+// resolveTemplates expands every string field's {{now}}, {{uuid}}, and
+// {{table.row.field}} placeholders in place, across all of tables.
+// {{now}} and {{uuid}} resolve first, in one pass shared by every field in
+// every row, before any {{table.row.field}} reference is resolved in a
+// second pass — so a reference to a field that's itself a generated uuid
+// or timestamp always sees the same value that field resolved to, rather
+// than generating (and disagreeing on) its own.
+func resolveTemplates(tables []*table) error {
+	now := time.Now().UTC().Format("2006-01-02 15:04:05")
+	byName := make(map[string]*table, len(tables))
+	for _, t := range tables {
+		byName[t.name] = t
+	}
+
+	for _, t := range tables {
+		for _, row := range t.rows {
+			for field, val := range row {
+				s, ok := val.(string)
+				if !ok {
+					continue
+				}
+				row[field] = templateRe.ReplaceAllStringFunc(s, func(m string) string {
+					switch strings.TrimSpace(m[2 : len(m)-2]) {
+					case "now":
+						return now
+					case "uuid":
+						return uuid.New().String()
+					default:
+						return m // resolved by the reference pass below
+					}
+				})
+			}
+		}
+	}
+
+	for _, t := range tables {
+		for rowName, row := range t.rows {
+			for field, val := range row {
+				s, ok := val.(string)
+				if !ok {
+					continue
+				}
+				resolved, err := resolveRefs(s, byName)
+				if err != nil {
+					return fmt.Errorf("fixture %s.%s.%s: %v", t.name, rowName, field, err)
+				}
+				row[field] = resolved
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRefs expands every {{table.row.field}} placeholder in s by
+// looking up the already-template-resolved value of that field.
+func resolveRefs(s string, byName map[string]*table) (string, error) {
+	var refErr error
+	result := templateRe.ReplaceAllStringFunc(s, func(m string) string {
+		expr := strings.TrimSpace(m[2 : len(m)-2])
+		if !refRe.MatchString(expr) {
+			refErr = fmt.Errorf("unknown template %s", m)
+			return m
+		}
+		parts := strings.SplitN(expr, ".", 3)
+		refTable, ok := byName[parts[0]]
+		if !ok {
+			refErr = fmt.Errorf("reference to unknown table %q in %s", parts[0], m)
+			return m
+		}
+		refRow, ok := refTable.rows[parts[1]]
+		if !ok {
+			refErr = fmt.Errorf("reference to unknown row %q in %s", parts[1], m)
+			return m
+		}
+		refVal, ok := refRow[parts[2]]
+		if !ok {
+			refErr = fmt.Errorf("reference to unknown field %q in %s", parts[2], m)
+			return m
+		}
+		return fmt.Sprintf("%v", refVal)
+	})
+	return result, refErr
+}
+
+// insertRow inserts row's fields into tableName, in alphabetical column
+// order (the order itself doesn't matter to Postgres; sorting just keeps
+// the generated SQL stable for anyone watching query logs).
+func insertRow(tx *sql.Tx, tableName string, row map[string]interface{}) error {
+	columns := make([]string, 0, len(row))
+	for col := range row {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	quotedCols := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		quotedCols[i] = quoteIdent(col)
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		values[i] = row[col]
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdent(tableName), strings.Join(quotedCols, ", "), strings.Join(placeholders, ", "))
+	_, err := tx.Exec(query, values...)
+	return err
+}
+
+// resetSequences resets every sequence backing one of tableName's columns
+// to the max value just loaded into that column, so the next real insert
+// continues past the fixture rows instead of colliding with them.
+func resetSequences(tx *sql.Tx, tableName string) error {
+	rows, err := tx.Query(`
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_name = $1
+		ORDER BY ordinal_position;
+	`, tableName)
+	if err != nil {
+		return fmt.Errorf("failed to list columns of %s for sequence reset: %v", tableName, err)
+	}
+	var columns []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, col)
+	}
+	rows.Close()
+
+	for _, col := range columns {
+		var seq sql.NullString
+		if err := tx.QueryRow(`SELECT pg_get_serial_sequence($1, $2)`, tableName, col).Scan(&seq); err != nil {
+			return fmt.Errorf("failed to resolve sequence for %s.%s: %v", tableName, col, err)
+		}
+		if !seq.Valid {
+			continue
+		}
+		query := fmt.Sprintf(`SELECT setval($1, COALESCE((SELECT MAX(%s) FROM %s), 1))`,
+			quoteIdent(col), quoteIdent(tableName))
+		if _, err := tx.Exec(query, seq.String); err != nil {
+			return fmt.Errorf("failed to reset sequence %s: %v", seq.String, err)
+		}
+	}
+	return nil
+}
+
+// quoteIdent double-quotes a Postgres identifier, escaping any embedded
+// double quotes.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,126 @@
+package fixtures
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveTemplatesNowAndUUID(t *testing.T) {
+	tables := []*table{
+		{
+			name:     "users",
+			rowOrder: []string{"alice"},
+			rows: map[string]map[string]interface{}{
+				"alice": {"id": "{{uuid}}", "created_at": "{{now}}", "name": "Alice"},
+			},
+		},
+	}
+
+	if err := resolveTemplates(tables); err != nil {
+		t.Fatalf("resolveTemplates: %v", err)
+	}
+
+	row := tables[0].rows["alice"]
+	if row["name"] != "Alice" {
+		t.Errorf("name = %v, want unchanged \"Alice\"", row["name"])
+	}
+	if row["created_at"] == "{{now}}" || row["created_at"] == "" {
+		t.Errorf("created_at was not resolved: %v", row["created_at"])
+	}
+	if row["id"] == "{{uuid}}" || row["id"] == "" {
+		t.Errorf("id was not resolved: %v", row["id"])
+	}
+}
+
+func TestResolveTemplatesCrossReference(t *testing.T) {
+	tables := []*table{
+		{
+			name:     "users",
+			rowOrder: []string{"alice"},
+			rows: map[string]map[string]interface{}{
+				"alice": {"id": "{{uuid}}", "name": "Alice"},
+			},
+		},
+		{
+			name:     "posts",
+			rowOrder: []string{"hello"},
+			rows: map[string]map[string]interface{}{
+				"hello": {"author_id": "{{users.alice.id}}", "title": "Hello"},
+			},
+		},
+	}
+
+	if err := resolveTemplates(tables); err != nil {
+		t.Fatalf("resolveTemplates: %v", err)
+	}
+
+	usersTable, postsTable := tables[0], tables[1]
+	gotAuthorID := postsTable.rows["hello"]["author_id"]
+	wantID := usersTable.rows["alice"]["id"]
+	if gotAuthorID != wantID {
+		t.Errorf("author_id = %v, want the resolved id %v", gotAuthorID, wantID)
+	}
+}
+
+func TestResolveTemplatesUnknownReferenceErrors(t *testing.T) {
+	tables := []*table{
+		{
+			name:     "posts",
+			rowOrder: []string{"hello"},
+			rows: map[string]map[string]interface{}{
+				"hello": {"author_id": "{{users.alice.id}}"},
+			},
+		},
+	}
+
+	if err := resolveTemplates(tables); err == nil {
+		t.Fatal("expected an error referencing a table with no fixture file")
+	}
+}
+
+func TestLoaderReadDirSortsRowsAndTables(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, dir, "posts.yaml", "zed:\n  title: Z\nalpha:\n  title: A\n")
+	writeFile(t, dir, "users.json", `{"bob": {"name": "Bob"}, "alice": {"name": "Alice"}}`)
+	writeFile(t, dir, "README.md", "not a fixture")
+
+	l := New(nil, Directory(dir))
+	tables, err := l.readDir()
+	if err != nil {
+		t.Fatalf("readDir: %v", err)
+	}
+
+	if len(tables) != 2 {
+		t.Fatalf("got %d tables, want 2 (README.md should be skipped)", len(tables))
+	}
+	if tables[0].name != "posts" || tables[1].name != "users" {
+		t.Fatalf("tables = [%s, %s], want [posts, users]", tables[0].name, tables[1].name)
+	}
+	if got, want := tables[0].rowOrder, []string{"alpha", "zed"}; !equalStrings(got, want) {
+		t.Errorf("posts rowOrder = %v, want %v", got, want)
+	}
+	if got, want := tables[1].rowOrder, []string{"alice", "bob"}; !equalStrings(got, want) {
+		t.Errorf("users rowOrder = %v, want %v", got, want)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
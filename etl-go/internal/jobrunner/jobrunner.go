@@ -0,0 +1,232 @@
+// Package jobrunner schedules recurring dump/ingest jobs on robfig/cron
+// expressions, on top of a clock.Clock so catch-up and jitter timing are
+// deterministically testable. Unlike services.Scheduler (which mails a
+// fixed set of reports), JobRunner is generic: callers register arbitrary
+// named jobs and it records each run in a persistent job_runs table,
+// skipping a job's scheduled firing if a previous run of the same job is
+// still in flight.
+package jobrunner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/IngeniosoHacker/OLAP-Data-lakehouse/etl-go/internal/clock"
+)
+
+// JobFunc is the work a job runs each time its schedule fires.
+type JobFunc func(ctx context.Context) error
+
+// JobSpec is one job's schedule and work function.
+type JobSpec struct {
+	// Name identifies the job in logs and the job_runs table; must be
+	// unique within a JobRunner.
+	Name string
+	// Schedule is a robfig/cron expression, e.g. "0 */4 * * *", or an
+	// "@every 1h"-style descriptor.
+	Schedule string
+	// Jitter is the maximum random delay applied before each firing, to
+	// avoid many jobs with the same schedule waking a downstream system
+	// at the same instant. Zero disables jitter.
+	Jitter time.Duration
+	// Run is the job's work. Its error is recorded in job_runs but does
+	// not stop future scheduled firings.
+	Run JobFunc
+}
+
+// RunStatus is the outcome of one recorded job_runs row.
+type RunStatus string
+
+const (
+	StatusRunning RunStatus = "running"
+	StatusSuccess RunStatus = "success"
+	StatusFailed  RunStatus = "failed"
+	StatusSkipped RunStatus = "skipped"
+)
+
+// JobRunner runs each registered job on its configured cron schedule,
+// backed by clk for Now()/After() so tests can drive catch-up, jitter, and
+// run timestamps deterministically with a clock.MockClock. The cron
+// scheduler's own firings follow the wall clock, not clk.
+type JobRunner struct {
+	cron *cron.Cron
+	clk  clock.Clock
+	runs *RunLogStore
+
+	rngMu sync.Mutex // guards rng: robfig/cron runs each job's callback in its own goroutine, and *rand.Rand isn't safe for concurrent use
+	rng   *rand.Rand
+
+	mu      sync.Mutex
+	jobs    map[string]JobSpec
+	running map[string]bool
+}
+
+// NewJobRunner returns a JobRunner that records runs in db's job_runs
+// table and schedules against clk. Pass clock.SystemClock{} in production;
+// a clock.MockClock lets tests advance time to trigger catch-up without
+// sleeping.
+func NewJobRunner(db *sql.DB, clk clock.Clock) *JobRunner {
+	return &JobRunner{
+		cron:    cron.New(),
+		clk:     clk,
+		runs:    NewRunLogStore(db),
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		jobs:    make(map[string]JobSpec),
+		running: make(map[string]bool),
+	}
+}
+
+// AddJob registers spec, validating that its schedule parses. AddJob must
+// be called before Start.
+func (jr *JobRunner) AddJob(spec JobSpec) error {
+	if _, err := cron.ParseStandard(spec.Schedule); err != nil {
+		return fmt.Errorf("job %q: invalid schedule %q: %v", spec.Name, spec.Schedule, err)
+	}
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	jr.jobs[spec.Name] = spec
+	return nil
+}
+
+// Start ensures job_runs exists, runs a catch-up pass for any job whose
+// next scheduled firing (since its last recorded run) has already passed,
+// then registers every job with the cron scheduler and begins running it
+// in the background.
+func (jr *JobRunner) Start() error {
+	if err := jr.runs.ensureTable(); err != nil {
+		return err
+	}
+
+	jr.mu.Lock()
+	specs := make([]JobSpec, 0, len(jr.jobs))
+	for _, spec := range jr.jobs {
+		specs = append(specs, spec)
+	}
+	jr.mu.Unlock()
+
+	for _, spec := range specs {
+		if err := jr.catchUp(spec); err != nil {
+			log.Printf("Warning: catch-up check for job %q failed: %v", spec.Name, err)
+		}
+
+		spec := spec // capture for the closure
+		_, err := jr.cron.AddFunc(spec.Schedule, func() {
+			jr.fire(spec)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule job %q: %v", spec.Name, err)
+		}
+		log.Printf("Scheduled job %q with cron expression %q", spec.Name, spec.Schedule)
+	}
+
+	jr.cron.Start()
+	return nil
+}
+
+// Stop stops the cron scheduler and waits for any in-flight job to finish.
+func (jr *JobRunner) Stop() {
+	ctx := jr.cron.Stop()
+	<-ctx.Done()
+}
+
+// RunNow runs name's job immediately, bypassing its schedule and jitter
+// but still subject to the overlap check and run-log recording.
+func (jr *JobRunner) RunNow(name string) error {
+	jr.mu.Lock()
+	spec, ok := jr.jobs[name]
+	jr.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("job %q not registered", name)
+	}
+	if err := jr.runs.ensureTable(); err != nil {
+		return err
+	}
+	jr.run(spec)
+	return nil
+}
+
+// catchUp runs spec immediately if its schedule's next firing after the
+// last recorded run has already passed by clk.Now(), which happens when
+// the runner was down across one or more of spec's scheduled firings.
+// A job with no recorded run is considered due for its first run.
+func (jr *JobRunner) catchUp(spec JobSpec) error {
+	sched, err := cron.ParseStandard(spec.Schedule)
+	if err != nil {
+		return err
+	}
+
+	lastRun, ok, err := jr.runs.LastRun(spec.Name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		jr.fire(spec)
+		return nil
+	}
+
+	if sched.Next(lastRun).After(jr.clk.Now()) {
+		return nil
+	}
+	log.Printf("Job %q missed one or more scheduled firings since %s; catching up now", spec.Name, lastRun)
+	jr.fire(spec)
+	return nil
+}
+
+// fire applies spec's jitter before running it, matching a normal cron
+// firing; catchUp and the cron callback both go through it so jittering
+// is consistent regardless of why a run was triggered.
+func (jr *JobRunner) fire(spec JobSpec) {
+	if spec.Jitter > 0 {
+		<-jr.clk.After(jr.jitterDelay(spec.Jitter))
+	}
+	jr.run(spec)
+}
+
+// jitterDelay returns a random duration in [0, max), guarding jr.rng since
+// cron runs concurrent jobs' callbacks in their own goroutines and
+// *rand.Rand is not safe for concurrent use.
+func (jr *JobRunner) jitterDelay(max time.Duration) time.Duration {
+	jr.rngMu.Lock()
+	defer jr.rngMu.Unlock()
+	return time.Duration(jr.rng.Int63n(int64(max)))
+}
+
+// run executes spec.Run, skipping it if a previous run of the same job is
+// still in flight, and records the outcome in job_runs.
+func (jr *JobRunner) run(spec JobSpec) {
+	jr.mu.Lock()
+	if jr.running[spec.Name] {
+		jr.mu.Unlock()
+		log.Printf("Job %q is still running from a previous firing; skipping this one", spec.Name)
+		if err := jr.runs.Record(spec.Name, jr.clk.Now(), jr.clk.Now(), StatusSkipped, nil); err != nil {
+			log.Printf("Warning: failed to record skipped run for job %q: %v", spec.Name, err)
+		}
+		return
+	}
+	jr.running[spec.Name] = true
+	jr.mu.Unlock()
+
+	defer func() {
+		jr.mu.Lock()
+		delete(jr.running, spec.Name)
+		jr.mu.Unlock()
+	}()
+
+	startedAt := jr.clk.Now()
+	runErr := spec.Run(context.Background())
+	status := StatusSuccess
+	if runErr != nil {
+		status = StatusFailed
+		log.Printf("Job %q failed: %v", spec.Name, runErr)
+	}
+	if err := jr.runs.Record(spec.Name, startedAt, jr.clk.Now(), status, runErr); err != nil {
+		log.Printf("Warning: failed to record run for job %q: %v", spec.Name, err)
+	}
+}
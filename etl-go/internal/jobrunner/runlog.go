@@ -0,0 +1,76 @@
+package jobrunner
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RunLogStore persists one row per job run to job_runs, so JobRunner can
+// tell on restart whether a job missed a scheduled firing (catch-up) and
+// so operators have a durable history of which jobs ran, when, and
+// whether they succeeded.
+type RunLogStore struct {
+	db *sql.DB
+}
+
+// NewRunLogStore returns a RunLogStore backed by db. Call ensureTable (or
+// rely on JobRunner.Start, which calls it) before the first Record.
+func NewRunLogStore(db *sql.DB) *RunLogStore {
+	return &RunLogStore{db: db}
+}
+
+// ensureTable creates job_runs if it doesn't already exist.
+func (s *RunLogStore) ensureTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS job_runs (
+			id          BIGSERIAL PRIMARY KEY,
+			job_name    TEXT NOT NULL,
+			started_at  TIMESTAMPTZ NOT NULL,
+			finished_at TIMESTAMPTZ NOT NULL,
+			status      TEXT NOT NULL,
+			error       TEXT
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create job_runs table: %v", err)
+	}
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS job_runs_job_name_started_at_idx ON job_runs (job_name, started_at DESC)`)
+	if err != nil {
+		return fmt.Errorf("failed to create job_runs index: %v", err)
+	}
+	return nil
+}
+
+// Record inserts one job_runs row for a completed (or skipped) run.
+func (s *RunLogStore) Record(jobName string, startedAt, finishedAt time.Time, status RunStatus, runErr error) error {
+	var errText sql.NullString
+	if runErr != nil {
+		errText = sql.NullString{String: runErr.Error(), Valid: true}
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO job_runs (job_name, started_at, finished_at, status, error) VALUES ($1, $2, $3, $4, $5)`,
+		jobName, startedAt, finishedAt, string(status), errText,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record run for job %q: %v", jobName, err)
+	}
+	return nil
+}
+
+// LastRun returns the started_at time of jobName's most recent recorded
+// run (of any status), and false if the job has never run.
+func (s *RunLogStore) LastRun(jobName string) (time.Time, bool, error) {
+	var startedAt time.Time
+	err := s.db.QueryRow(
+		`SELECT started_at FROM job_runs WHERE job_name = $1 ORDER BY started_at DESC LIMIT 1`,
+		jobName,
+	).Scan(&startedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to look up last run for job %q: %v", jobName, err)
+	}
+	return startedAt, true, nil
+}
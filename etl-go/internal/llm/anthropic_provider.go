@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// AnthropicProvider implements Provider against Anthropic's Messages API.
+type AnthropicProvider struct {
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewAnthropicProvider creates an AnthropicProvider. An empty model
+// defaults to "claude-3-5-sonnet-20241022".
+func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
+	if model == "" {
+		model = "claude-3-5-sonnet-20241022"
+	}
+	return &AnthropicProvider{
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type anthropicMessageRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicMessageResponse struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// AnalyzeSchema sends tables to Anthropic's Messages API and parses the
+// response text into a ColumnAnalysisResult.
+func (p *AnthropicProvider) AnalyzeSchema(ctx context.Context, tables []TableSchema) (*ColumnAnalysisResult, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("anthropic: ANTHROPIC_API_KEY is not set")
+	}
+
+	prompt := buildSchemaPrompt(tables)
+	reqBody, err := json.Marshal(anthropicMessageRequest{
+		Model:     p.model,
+		MaxTokens: 1024,
+		Messages:  []anthropicMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: failed to encode request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("anthropic: unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	var msgResp anthropicMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&msgResp); err != nil {
+		return nil, fmt.Errorf("anthropic: failed to decode response: %v", err)
+	}
+	if len(msgResp.Content) == 0 {
+		return nil, fmt.Errorf("anthropic: response had no content")
+	}
+
+	return parseSchemaResponse(msgResp.Content[0].Text)
+}
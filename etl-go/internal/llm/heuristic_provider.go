@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// HeuristicProvider implements Provider with the substring-matching rules
+// ETLService.AnalyzeColumnsWithLLM used before real LLM backends existed.
+// It's the default Provider (see NewFromEnv) so deployments without LLM
+// credentials keep working exactly as before.
+type HeuristicProvider struct{}
+
+// NewHeuristicProvider creates a HeuristicProvider.
+func NewHeuristicProvider() *HeuristicProvider {
+	return &HeuristicProvider{}
+}
+
+// AnalyzeSchema classifies each table's columns by substring-matching
+// their names against common dimension/fact vocabulary, records each
+// table's column named "id" (case-insensitively; "id" when there is none)
+// as that table's surrogate key, and defaults
+// every dimension to a type1 (overwrite-in-place) SCD. It never infers
+// Relationships, since that requires actually reasoning about the tables
+// rather than matching column names.
+func (p *HeuristicProvider) AnalyzeSchema(ctx context.Context, tables []TableSchema) (*ColumnAnalysisResult, error) {
+	result := &ColumnAnalysisResult{
+		Dimensions:    make(map[string]string),
+		SurrogateKeys: make(map[string]string),
+		SCDTypes:      make(map[string]string),
+	}
+	if len(tables) > 0 {
+		result.TableName = tables[0].TableName
+	}
+
+	for _, table := range tables {
+		surrogateKey := "id"
+		for _, col := range table.Columns {
+			colLower := strings.ToLower(col.Name)
+
+			switch {
+			case colLower == "id":
+				surrogateKey = col.Name
+			case strings.Contains(colLower, "name") ||
+				strings.Contains(colLower, "desc") ||
+				strings.Contains(colLower, "category") ||
+				strings.Contains(colLower, "type") ||
+				strings.Contains(colLower, "date"):
+				result.Dimensions[col.Name] = "dimension"
+				result.SCDTypes[col.Name] = "type1"
+			case strings.Contains(colLower, "amount") ||
+				strings.Contains(colLower, "price") ||
+				strings.Contains(colLower, "quantity") ||
+				strings.Contains(colLower, "count") ||
+				strings.Contains(colLower, "total"):
+				result.Facts = append(result.Facts, col.Name)
+			}
+		}
+		result.SurrogateKeys[table.TableName] = surrogateKey
+
+		if len(result.Facts) > 0 && result.Grain == "" {
+			result.Grain = fmt.Sprintf("one row per %s", table.TableName)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,126 @@
+// Package llm abstracts the backend ETLService.AnalyzeColumnsWithLLM uses
+// to infer star-schema structure from a table's columns and sample rows,
+// so the ETL pipeline isn't hard-wired to a single provider (or, as
+// before, to string-matching heuristics pretending to be one).
+// Implementations exist for OpenAI, Anthropic, and a local Ollama
+// endpoint, plus a HeuristicProvider fallback that keeps the original
+// substring-matching behavior when no LLM is configured.
+package llm
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// ColumnInfo is one column's name and inferred SQL type, as passed to
+// Provider.AnalyzeSchema.
+type ColumnInfo struct {
+	Name string
+	Type string
+}
+
+// TableSchema bundles one table's column metadata and a bounded, redacted
+// sample of its rows for Provider.AnalyzeSchema. Build one with
+// BuildTableSchema rather than constructing it directly, so sampling and
+// PII redaction stay consistent across callers.
+type TableSchema struct {
+	TableName  string
+	Columns    []ColumnInfo
+	SampleRows []map[string]interface{}
+}
+
+// ColumnAnalysisResult is the star-schema structure a Provider infers for
+// one or more tables.
+type ColumnAnalysisResult struct {
+	TableName     string            `json:"table_name"`
+	Dimensions    map[string]string `json:"dimensions"`     // column -> dimension type, e.g. "dimension"
+	Facts         []string          `json:"facts"`          // fact column names
+	SurrogateKeys map[string]string `json:"surrogate_keys"` // table name -> suggested surrogate key column
+	SCDTypes      map[string]string `json:"scd_types"`      // dimension column -> SCD type, e.g. "type1"/"type2"
+	Grain         string            `json:"grain"`          // natural-language description of one fact row
+	Relationships []string          `json:"relationships"`  // e.g. "orders.customer_id -> customers.id"
+}
+
+// Provider is implemented by every LLM backend ETLService can delegate
+// star-schema analysis to.
+type Provider interface {
+	// AnalyzeSchema infers star-schema structure for tables. Passing more
+	// than one table lets a Provider infer cross-table Relationships
+	// instead of seeing each table in isolation.
+	AnalyzeSchema(ctx context.Context, tables []TableSchema) (*ColumnAnalysisResult, error)
+}
+
+// NewFromEnv builds the Provider named by LLM_PROVIDER ("openai",
+// "anthropic", or "ollama"); an unset or unrecognized value falls back to
+// HeuristicProvider so deployments without LLM credentials keep working.
+func NewFromEnv() Provider {
+	switch strings.ToLower(os.Getenv("LLM_PROVIDER")) {
+	case "openai":
+		return NewOpenAIProvider(os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_MODEL"))
+	case "anthropic":
+		return NewAnthropicProvider(os.Getenv("ANTHROPIC_API_KEY"), os.Getenv("ANTHROPIC_MODEL"))
+	case "ollama":
+		return NewOllamaProvider(os.Getenv("OLLAMA_ENDPOINT"), os.Getenv("OLLAMA_MODEL"))
+	default:
+		return NewHeuristicProvider()
+	}
+}
+
+// MaxSampleRows bounds how many rows BuildTableSchema includes per table,
+// keeping prompts small and limiting how much data a redaction bug could
+// leak to a remote Provider.
+const MaxSampleRows = 5
+
+// RedactFunc redacts a single sampled row before BuildTableSchema includes
+// it in a TableSchema. Callers can pass a stricter implementation (e.g. one
+// that also masks columns discovered by InferColumnType rather than by
+// name) in place of DefaultRedact.
+type RedactFunc func(row map[string]interface{}) map[string]interface{}
+
+// piiColumnMarkers are substrings in a column name that mark it as likely
+// holding PII, for DefaultRedact.
+var piiColumnMarkers = []string{"email", "phone", "ssn", "address", "dob", "birth", "name"}
+
+// DefaultRedact masks any column whose name contains a piiColumnMarkers
+// substring and passes every other column through unchanged.
+func DefaultRedact(row map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(row))
+	for col, val := range row {
+		if looksLikePII(col) {
+			redacted[col] = "[REDACTED]"
+			continue
+		}
+		redacted[col] = val
+	}
+	return redacted
+}
+
+func looksLikePII(colName string) bool {
+	lower := strings.ToLower(colName)
+	for _, marker := range piiColumnMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildTableSchema samples up to MaxSampleRows of rows through redact (or
+// DefaultRedact if nil) to build the TableSchema a Provider expects.
+func BuildTableSchema(tableName string, columns []ColumnInfo, rows []map[string]interface{}, redact RedactFunc) TableSchema {
+	if redact == nil {
+		redact = DefaultRedact
+	}
+
+	n := len(rows)
+	if n > MaxSampleRows {
+		n = MaxSampleRows
+	}
+	sample := make([]map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		sample[i] = redact(rows[i])
+	}
+
+	return TableSchema{TableName: tableName, Columns: columns, SampleRows: sample}
+}
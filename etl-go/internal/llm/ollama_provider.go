@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaProvider implements Provider against a local Ollama server's
+// /api/generate endpoint, for deployments that want schema analysis
+// without sending table samples to a remote API.
+type OllamaProvider struct {
+	endpoint   string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOllamaProvider creates an OllamaProvider against endpoint (default
+// "http://localhost:11434" if empty) using model (default "llama3").
+func NewOllamaProvider(endpoint, model string) *OllamaProvider {
+	if endpoint == "" {
+		endpoint = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "llama3"
+	}
+	return &OllamaProvider{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		model:      model,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+type ollamaGenerateRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Format string `json:"format"`
+	Stream bool   `json:"stream"`
+}
+
+type ollamaGenerateResponse struct {
+	Response string `json:"response"`
+}
+
+// AnalyzeSchema sends tables to the local Ollama server's /api/generate
+// endpoint, requesting a JSON-formatted response, and parses it into a
+// ColumnAnalysisResult.
+func (p *OllamaProvider) AnalyzeSchema(ctx context.Context, tables []TableSchema) (*ColumnAnalysisResult, error) {
+	reqBody, err := json.Marshal(ollamaGenerateRequest{
+		Model:  p.model,
+		Prompt: buildSchemaPrompt(tables),
+		Format: "json",
+		Stream: false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ollama: failed to encode request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/api/generate", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("ollama: failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama: unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	var genResp ollamaGenerateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
+		return nil, fmt.Errorf("ollama: failed to decode response: %v", err)
+	}
+
+	return parseSchemaResponse(genResp.Response)
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// OpenAIProvider implements Provider against OpenAI's chat completions API.
+type OpenAIProvider struct {
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates an OpenAIProvider. An empty model defaults to
+// "gpt-4o-mini".
+func NewOpenAIProvider(apiKey, model string) *OpenAIProvider {
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return &OpenAIProvider{
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type openAIChatRequest struct {
+	Model          string               `json:"model"`
+	Messages       []openAIChatMessage  `json:"messages"`
+	ResponseFormat openAIResponseFormat `json:"response_format"`
+}
+
+type openAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// AnalyzeSchema sends tables to OpenAI's chat completions API, requesting a
+// JSON-mode response, and parses it into a ColumnAnalysisResult.
+func (p *OpenAIProvider) AnalyzeSchema(ctx context.Context, tables []TableSchema) (*ColumnAnalysisResult, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("openai: OPENAI_API_KEY is not set")
+	}
+
+	reqBody, err := json.Marshal(openAIChatRequest{
+		Model: p.model,
+		Messages: []openAIChatMessage{
+			{Role: "user", Content: buildSchemaPrompt(tables)},
+		},
+		ResponseFormat: openAIResponseFormat{Type: "json_object"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to encode request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/chat/completions", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openai: request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("openai: unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	var chatResp openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("openai: failed to decode response: %v", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("openai: response had no choices")
+	}
+
+	return parseSchemaResponse(chatResp.Choices[0].Message.Content)
+}
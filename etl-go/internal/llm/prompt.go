@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// buildSchemaPrompt renders tables into the strict-JSON-response prompt
+// every remote Provider sends, so OpenAI, Anthropic, and Ollama all ask the
+// model the same question and differ only in transport and response
+// parsing.
+func buildSchemaPrompt(tables []TableSchema) string {
+	var b strings.Builder
+	b.WriteString("You are a data warehouse architect. Given the following table schemas and sample rows, ")
+	b.WriteString("identify dimension columns, fact columns, a suggested surrogate key per table, ")
+	b.WriteString("slowly-changing-dimension (SCD) types for dimension columns, the grain of the fact table, ")
+	b.WriteString("and any cross-table relationships implied by foreign-key-like column names.\n\n")
+
+	for _, t := range tables {
+		fmt.Fprintf(&b, "Table: %s\nColumns:\n", t.TableName)
+		for _, c := range t.Columns {
+			fmt.Fprintf(&b, "  - %s (%s)\n", c.Name, c.Type)
+		}
+		if len(t.SampleRows) > 0 {
+			if sampleJSON, err := json.Marshal(t.SampleRows); err == nil {
+				fmt.Fprintf(&b, "Sample rows: %s\n", sampleJSON)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("Respond with ONLY a JSON object matching this shape, no prose or markdown fencing:\n")
+	b.WriteString(`{"table_name": string, "dimensions": {"<column>": "<dimension type>"}, "facts": ["<column>", ...], ` +
+		`"surrogate_keys": {"<table>": "<column>"}, "scd_types": {"<column>": "type1|type2|type3"}, ` +
+		`"grain": "<one sentence>", "relationships": ["<table>.<column> -> <table>.<column>", ...]}`)
+
+	return b.String()
+}
+
+// parseSchemaResponse validates raw (a Provider's strict-JSON response)
+// against ColumnAnalysisResult.
+func parseSchemaResponse(raw string) (*ColumnAnalysisResult, error) {
+	var result ColumnAnalysisResult
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM schema response: %v (response: %s)", err, raw)
+	}
+	return &result, nil
+}
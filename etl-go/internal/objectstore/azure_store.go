@@ -0,0 +1,109 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// AzureBlobStore implements ObjectStore against an Azure Blob Storage
+// container, backing the azblob:// URI scheme.
+type AzureBlobStore struct {
+	container     azblob.ContainerURL
+	containerName string
+	credential    *azblob.SharedKeyCredential
+}
+
+// NewAzureBlobStore creates an AzureBlobStore for containerName in
+// accountName, authenticating with accountKey.
+func NewAzureBlobStore(accountName, accountKey, containerName string) (*AzureBlobStore, error) {
+	credential, err := azblob.NewSharedKeyCredential(accountName, accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure Blob credential: %v", err)
+	}
+
+	containerURL, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", accountName, containerName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Azure Blob container URL: %v", err)
+	}
+
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	return &AzureBlobStore{
+		container:     azblob.NewContainerURL(*containerURL, pipeline),
+		containerName: containerName,
+		credential:    credential,
+	}, nil
+}
+
+// Put uploads r as a block blob named key.
+func (s *AzureBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	blob := s.container.NewBlockBlobURL(key)
+	_, err := azblob.UploadStreamToBlockBlob(ctx, r, blob, azblob.UploadStreamToBlockBlobOptions{
+		BufferSize:      4 * 1024 * 1024,
+		MaxBuffers:      4,
+		BlobHTTPHeaders: azblob.BlobHTTPHeaders{ContentType: contentType},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to Azure Blob container %s: %v", key, s.containerName, err)
+	}
+	return nil
+}
+
+// Get downloads key.
+func (s *AzureBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	blob := s.container.NewBlobURL(key)
+	resp, err := blob.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s from Azure Blob container %s: %v", key, s.containerName, err)
+	}
+	return resp.Body(azblob.RetryReaderOptions{}), nil
+}
+
+// List returns every blob name under prefix.
+func (s *AzureBlobStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := s.container.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: prefix})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Azure Blob container %s: %v", s.containerName, err)
+		}
+		for _, item := range resp.Segment.BlobItems {
+			keys = append(keys, item.Name)
+		}
+		marker = resp.NextMarker
+	}
+	return keys, nil
+}
+
+// Delete removes key.
+func (s *AzureBlobStore) Delete(ctx context.Context, key string) error {
+	blob := s.container.NewBlobURL(key)
+	if _, err := blob.Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{}); err != nil {
+		return fmt.Errorf("failed to delete %s from Azure Blob container %s: %v", key, s.containerName, err)
+	}
+	return nil
+}
+
+// PresignGet returns a time-limited SAS URL for downloading key.
+func (s *AzureBlobStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	sas, err := azblob.BlobSASSignatureValues{
+		Protocol:      azblob.SASProtocolHTTPS,
+		ExpiryTime:    time.Now().Add(expiry),
+		ContainerName: s.containerName,
+		BlobName:      key,
+		Permissions:   azblob.BlobSASPermissions{Read: true}.String(),
+	}.NewSASQueryParameters(s.credential)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s in Azure Blob container %s: %v", key, s.containerName, err)
+	}
+
+	blob := s.container.NewBlobURL(key)
+	parts := azblob.NewBlobURLParts(blob.URL())
+	parts.SAS = sas
+	u := parts.URL()
+	return u.String(), nil
+}
@@ -0,0 +1,88 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStore implements ObjectStore against a Google Cloud Storage bucket,
+// backing the gs:// URI scheme.
+type GCSStore struct {
+	client *storage.Client
+	bucket string
+}
+
+// NewGCSStore creates a GCSStore for bucket, using the environment's default
+// application credentials.
+func NewGCSStore(ctx context.Context, bucket string) (*GCSStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %v", err)
+	}
+	return &GCSStore{client: client, bucket: bucket}, nil
+}
+
+// Put streams r to the object named key.
+func (s *GCSStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	w := s.client.Bucket(s.bucket).Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write %s to GCS bucket %s: %v", key, s.bucket, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finalize %s in GCS bucket %s: %v", key, s.bucket, err)
+	}
+	return nil
+}
+
+// Get opens key for reading.
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := s.client.Bucket(s.bucket).Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s from GCS bucket %s: %v", key, s.bucket, err)
+	}
+	return r, nil
+}
+
+// List returns every object name under prefix.
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	it := s.client.Bucket(s.bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GCS bucket %s: %v", s.bucket, err)
+		}
+		keys = append(keys, attrs.Name)
+	}
+	return keys, nil
+}
+
+// Delete removes key.
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	if err := s.client.Bucket(s.bucket).Object(key).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete %s from GCS bucket %s: %v", key, s.bucket, err)
+	}
+	return nil
+}
+
+// PresignGet returns a time-limited signed URL for downloading key.
+func (s *GCSStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	u, err := s.client.Bucket(s.bucket).SignedURL(key, &storage.SignedURLOptions{
+		Method:  "GET",
+		Expires: time.Now().Add(expiry),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s in GCS bucket %s: %v", key, s.bucket, err)
+	}
+	return u, nil
+}
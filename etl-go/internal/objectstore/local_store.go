@@ -0,0 +1,123 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore implements ObjectStore against a directory on the local
+// filesystem. It backs the file:// URI scheme and is mainly useful for tests
+// and single-node deployments that don't have a MinIO/S3/GCS/Azure endpoint
+// available.
+type LocalStore struct {
+	baseDir string
+}
+
+// NewLocalStore creates a LocalStore rooted at baseDir, creating it if it
+// doesn't already exist.
+func NewLocalStore(baseDir string) (*LocalStore, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create local store directory %s: %v", baseDir, err)
+	}
+	return &LocalStore{baseDir: baseDir}, nil
+}
+
+// resolve maps an object key to a path under baseDir. ".." elements are
+// cleaned against a virtual root first, so no key resolves outside baseDir;
+// only an empty key is rejected.
+func (s *LocalStore) resolve(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" {
+		return "", fmt.Errorf("empty object key")
+	}
+	return filepath.Join(s.baseDir, cleaned), nil
+}
+
+// Put writes r to baseDir/key, creating any parent directories it needs.
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %v", key, err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// Get opens baseDir/key for reading.
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// List returns every file under baseDir whose key (its path relative to
+// baseDir) starts with prefix.
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(s.baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.baseDir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local store %s: %v", s.baseDir, err)
+	}
+	return keys, nil
+}
+
+// Delete removes baseDir/key.
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete %s: %v", path, err)
+	}
+	return nil
+}
+
+// PresignGet has no real equivalent on a local filesystem, so it returns a
+// file:// URI pointing at the resolved path; expiry is ignored.
+func (s *LocalStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	path, err := s.resolve(key)
+	if err != nil {
+		return "", err
+	}
+	return "file://" + path, nil
+}
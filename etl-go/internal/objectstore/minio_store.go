@@ -0,0 +1,80 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// MinIOStore implements ObjectStore against a MinIO or S3-compatible
+// endpoint. It backs the s3:// URI scheme and preserves the upload/download
+// behavior ETLService used before ObjectStore existed.
+type MinIOStore struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewMinIOStore creates a MinIOStore for bucket. secure selects https vs
+// http for endpoint, matching minio.Options.Secure.
+func NewMinIOStore(endpoint, accessKey, secretKey, bucket string, secure bool) (*MinIOStore, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: secure,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %v", err)
+	}
+	return &MinIOStore{client: client, bucket: bucket}, nil
+}
+
+// Put uploads r under key, streaming rather than buffering.
+func (s *MinIOStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to put %s to MinIO bucket %s: %v", key, s.bucket, err)
+	}
+	return nil
+}
+
+// Get opens key for reading; the caller must close the returned reader.
+func (s *MinIOStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s from MinIO bucket %s: %v", key, s.bucket, err)
+	}
+	return obj, nil
+}
+
+// List returns every key under prefix.
+func (s *MinIOStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list MinIO bucket %s: %v", s.bucket, obj.Err)
+		}
+		keys = append(keys, obj.Key)
+	}
+	return keys, nil
+}
+
+// Delete removes key.
+func (s *MinIOStore) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s from MinIO bucket %s: %v", key, s.bucket, err)
+	}
+	return nil
+}
+
+// PresignGet returns a time-limited URL for downloading key directly from
+// the MinIO/S3 endpoint.
+func (s *MinIOStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s in MinIO bucket %s: %v", key, s.bucket, err)
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,67 @@
+// Package objectstore abstracts the blob storage backend the lakehouse
+// writes raw and columnar data to, so ETLService isn't hard-wired to a single
+// MinIO bucket. Implementations exist for MinIO/S3, GCS, Azure Blob, and the
+// local filesystem (the last mainly for tests and single-node deployments).
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ObjectStore is the interface every blob storage backend implements.
+// ETLService writes through an ObjectStore instead of a concrete client so
+// callers can swap MinIO/S3 for GCS, Azure Blob, or a local directory without
+// touching the ETL pipeline code.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// NewFromURI creates the ObjectStore implementation matching rawURI's
+// scheme:
+//
+//   - s3://bucket     MinIO/S3, endpoint and credentials from
+//     MINIO_ENDPOINT/MINIO_ACCESS_KEY/MINIO_SECRET_KEY/MINIO_SECURE
+//   - gs://bucket     GCS, credentials from the environment's default
+//     application credentials
+//   - azblob://container   Azure Blob, credentials from
+//     AZURE_STORAGE_ACCOUNT/AZURE_STORAGE_KEY
+//   - file:///path    local filesystem, rooted at /path
+//
+// Credentials intentionally come from the environment rather than the URI
+// itself, matching how NewETLService's callers already supply MinIO
+// credentials.
+func NewFromURI(rawURI string) (ObjectStore, error) {
+	u, err := url.Parse(rawURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object store URI %q: %v", rawURI, err)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		secure, _ := strconv.ParseBool(os.Getenv("MINIO_SECURE"))
+		return NewMinIOStore(os.Getenv("MINIO_ENDPOINT"), os.Getenv("MINIO_ACCESS_KEY"), os.Getenv("MINIO_SECRET_KEY"), u.Host, secure)
+	case "gs":
+		return NewGCSStore(context.Background(), u.Host)
+	case "azblob":
+		return NewAzureBlobStore(os.Getenv("AZURE_STORAGE_ACCOUNT"), os.Getenv("AZURE_STORAGE_KEY"), u.Host)
+	case "file":
+		path := u.Path
+		if path == "" {
+			path = strings.TrimPrefix(rawURI, "file://")
+		}
+		return NewLocalStore(path)
+	default:
+		return nil, fmt.Errorf("unsupported object store scheme %q (expected s3, gs, azblob, or file)", u.Scheme)
+	}
+}
@@ -0,0 +1,75 @@
+// Package progress reports read/load progress for long-running ETL file
+// sources: a live terminal bar via github.com/cheggaaa/pb/v3 when stderr
+// is attached to a terminal (detected with golang.org/x/term), or periodic
+// JSON log lines otherwise, so progress stays visible when stderr is
+// captured by a log aggregator instead of a terminal.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+	"golang.org/x/term"
+)
+
+// logInterval is how often the non-TTY fallback in NewReader emits a JSON
+// progress line.
+const logInterval = 5 * time.Second
+
+// NewReader wraps r so reading from it reports progress under label. total
+// is the source's size in bytes if known, or 0 if not (the terminal bar
+// then shows bytes read without a percentage; the JSON fallback omits
+// "total"). Callers must Close the returned reader to flush/finish the bar.
+func NewReader(r io.Reader, total int64, label string) io.ReadCloser {
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		bar := pb.Full.Start64(total)
+		bar.Set(pb.Bytes, true)
+		bar.SetTemplateString(fmt.Sprintf(`{{ "%s" }} {{counters . }} {{bar . }} {{percent . }} {{speed . }}`, label))
+		bar.SetWriter(os.Stderr)
+		return bar.NewProxyReader(r)
+	}
+	return &jsonReader{r: r, total: total, label: label, last: time.Now()}
+}
+
+// jsonReader is the non-TTY fallback NewReader returns: it passes reads
+// through unchanged, periodically logging a JSON progress line to stderr.
+type jsonReader struct {
+	r     io.Reader
+	total int64
+	label string
+	read  int64
+	last  time.Time
+}
+
+type logLine struct {
+	Label string `json:"label"`
+	Read  int64  `json:"read"`
+	Total int64  `json:"total,omitempty"`
+}
+
+func (p *jsonReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	p.read += int64(n)
+
+	if time.Since(p.last) >= logInterval || err == io.EOF {
+		line, marshalErr := json.Marshal(logLine{Label: p.label, Read: p.read, Total: p.total})
+		if marshalErr == nil {
+			fmt.Fprintln(os.Stderr, string(line))
+		}
+		p.last = time.Now()
+	}
+
+	return n, err
+}
+
+// Close closes the underlying reader if it implements io.Closer.
+func (p *jsonReader) Close() error {
+	if c, ok := p.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
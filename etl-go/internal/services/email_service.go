@@ -1,30 +1,564 @@
-package main
+package services
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	htmltemplate "html/template"
+	"io"
 	"log"
+	"math/rand"
+	"net"
+	"net/textproto"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"gopkg.in/mail.v2"
+	"gopkg.in/yaml.v2"
 )
 
+// Recipient represents an email recipient
+type Recipient struct {
+	Name        string   `yaml:"name"`
+	Email       string   `yaml:"email"`
+	Department  string   `yaml:"department"`
+	ReportTypes []string `yaml:"report_types"`
+}
+
+// SMTPConfig represents SMTP server configuration
+type SMTPConfig struct {
+	Host               string `yaml:"host"`
+	Port               int    `yaml:"port"`
+	Username           string `yaml:"username"`
+	Password           string `yaml:"password"`
+	From               string `yaml:"from"`
+	Encryption         string `yaml:"encryption"` // Options: none, ssl, starttls
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	ServerName         string `yaml:"server_name"` // overrides the TLS ServerName, useful for self-signed internal relays
+}
+
+// ReportConfig represents report-specific settings
+type ReportConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Schedule     string   `yaml:"schedule"` // standard 5-field cron expression, interpreted in Settings.Timezone
+	Subject      string   `yaml:"subject"`
+	Template     string   `yaml:"template"`
+	HTMLTemplate string   `yaml:"html_template"`  // optional text/html alternative body
+	InlineImages []string `yaml:"inline_images"`  // paths embedded via cid: and referenced from HTMLTemplate
+	SourceType   string   `yaml:"source_type"`    // "sql" or "file"; selects which ETLService method the scheduler runs before mailing
+	SourceQuery  string   `yaml:"source_query"`   // used when SourceType is "sql"
+	SourceFile   string   `yaml:"source_file"`    // used when SourceType is "file"
+}
+
+// InboundConfig configures the InboundMailer subsystem: polling a mailbox (or
+// accepting webhook posts) for subscription commands and bounce/DSN reports.
+type InboundConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	IMAPHost        string   `yaml:"imap_host"`
+	IMAPPort        int      `yaml:"imap_port"`
+	Username        string   `yaml:"username"`
+	Password        string   `yaml:"password"`
+	Mailbox         string   `yaml:"mailbox"`           // defaults to INBOX
+	AllowedSenders  []string `yaml:"allowed_senders"`   // command emails from any other sender are ignored
+	WebhookPath     string   `yaml:"webhook_path"`      // HTTP path InboundMailer.ServeHTTP is mounted on
+	BounceThreshold int      `yaml:"bounce_threshold"`  // bounces recorded before a recipient is auto-disabled
+}
+
+// EmailConfig represents the complete email configuration
+type EmailConfig struct {
+	Recipients []Recipient             `yaml:"recipients"`
+	SMTP       SMTPConfig              `yaml:"smtp"`
+	Reports    map[string]ReportConfig `yaml:"reports"`
+	Inbound    InboundConfig           `yaml:"inbound"`
+	Settings   struct {
+		Timezone           string   `yaml:"timezone"`
+		RetryAttempts      int      `yaml:"retry_attempts"`
+		TimeoutSeconds     int      `yaml:"timeout_seconds"`
+		EnableLogging      bool     `yaml:"enable_logging"`
+		Attachments        []string `yaml:"attachments"`
+		Concurrency        int      `yaml:"concurrency"`           // max recipients sent to in parallel, default 5
+		RateLimitPerSecond int      `yaml:"rate_limit_per_second"` // max sends/sec across all workers, 0 disables limiting
+		APIBearerToken     string   `yaml:"api_bearer_token"`      // required Authorization: Bearer token for the apiserver's POST /api/tx; empty disables auth
+		APIMaxRequestBytes int64    `yaml:"api_max_request_bytes"` // max POST /api/tx body size, 0 uses apiserver's default
+	} `yaml:"settings"`
+}
+
+// LoadEmailConfig loads the email configuration from a YAML file
+func LoadEmailConfig(filePath string) (*EmailConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var config EmailConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %v", err)
+	}
+
+	return &config, nil
+}
+
+// SaveEmailConfig writes the configuration back to a YAML file, matching the
+// format LoadEmailConfig reads. It's used by InboundMailer to persist
+// subscription changes recipients make by replying to a report.
+func SaveEmailConfig(filePath string, config *EmailConfig) error {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	return nil
+}
+
+// GetRecipientsByReportType returns recipients who should receive a specific report type
+func (ec *EmailConfig) GetRecipientsByReportType(reportType string) []Recipient {
+	var recipients []Recipient
+	for _, recipient := range ec.Recipients {
+		for _, rt := range recipient.ReportTypes {
+			if rt == reportType {
+				recipients = append(recipients, recipient)
+				break
+			}
+		}
+	}
+	return recipients
+}
+
+// defaultTemplateData builds the standard name/date/month/email/dept template
+// variables for recipient, then merges each map in extra over them in order,
+// so callers (like the transactional apiserver) can override or add their own
+// variables.
+func defaultTemplateData(recipient Recipient, extra ...map[string]interface{}) map[string]interface{} {
+	data := map[string]interface{}{
+		"name":  recipient.Name,
+		"date":  time.Now().Format("2006-01-02"),
+		"month": time.Now().Format("January 2006"),
+		"email": recipient.Email,
+		"dept":  recipient.Department,
+	}
+	for _, m := range extra {
+		for k, v := range m {
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// FormatEmailSubject formats the email subject with template variables. extra
+// maps are merged over the defaults; see defaultTemplateData.
+func (ec *EmailConfig) FormatEmailSubject(reportType string, recipient Recipient, extra ...map[string]interface{}) (string, error) {
+	reportConfig, exists := ec.Reports[reportType]
+	if !exists {
+		return "", fmt.Errorf("report type '%s' not found in config", reportType)
+	}
+
+	data := defaultTemplateData(recipient, extra...)
+
+	tmpl, err := template.New("subject").Parse(reportConfig.Subject)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse subject template: %v", err)
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", fmt.Errorf("failed to execute subject template: %v", err)
+	}
+
+	return result.String(), nil
+}
+
+// FormatEmailBody formats the email body with template variables. extra maps
+// are merged over the defaults; see defaultTemplateData.
+func (ec *EmailConfig) FormatEmailBody(reportType string, recipient Recipient, extra ...map[string]interface{}) (string, error) {
+	reportConfig, exists := ec.Reports[reportType]
+	if !exists {
+		return "", fmt.Errorf("report type '%s' not found in config", reportType)
+	}
+
+	data := defaultTemplateData(recipient, extra...)
+
+	tmpl, err := template.New("body").Parse(reportConfig.Template)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse body template: %v", err)
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", fmt.Errorf("failed to execute body template: %v", err)
+	}
+
+	return result.String(), nil
+}
+
+// FormatEmailHTMLBody formats the HTML alternative body with template
+// variables. extra maps are merged over the defaults; see
+// defaultTemplateData. It returns an empty string when the report type has no
+// HTMLTemplate configured.
+//
+// This uses html/template rather than FormatEmailBody's text/template:
+// extra (and, via SendTransactionalReport, arbitrary caller-supplied
+// TemplateVars) ends up interpolated into an HTML email body, so field
+// values must be escaped per HTML context to avoid markup/script
+// injection into mail sent from this system's domain.
+func (ec *EmailConfig) FormatEmailHTMLBody(reportType string, recipient Recipient, extra ...map[string]interface{}) (string, error) {
+	reportConfig, exists := ec.Reports[reportType]
+	if !exists {
+		return "", fmt.Errorf("report type '%s' not found in config", reportType)
+	}
+	if reportConfig.HTMLTemplate == "" {
+		return "", nil
+	}
+
+	data := defaultTemplateData(recipient, extra...)
+
+	tmpl, err := htmltemplate.New("htmlBody").Parse(reportConfig.HTMLTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML body template: %v", err)
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", fmt.Errorf("failed to execute HTML body template: %v", err)
+	}
+
+	return result.String(), nil
+}
+
+// ValidateEmail validates email format
+func ValidateEmail(email string) bool {
+	re := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+	return re.MatchString(email)
+}
+
+// ValidateConfig validates the email configuration
+func (ec *EmailConfig) ValidateConfig() []string {
+	var errors []string
+
+	for i, recipient := range ec.Recipients {
+		if recipient.Name == "" {
+			errors = append(errors, fmt.Sprintf("recipient %d has empty name", i))
+		}
+		if recipient.Email == "" {
+			errors = append(errors, fmt.Sprintf("recipient %d has empty email", i))
+		} else if !ValidateEmail(recipient.Email) {
+			errors = append(errors, fmt.Sprintf("recipient %d has invalid email format: %s", i, recipient.Email))
+		}
+		if len(recipient.ReportTypes) == 0 {
+			errors = append(errors, fmt.Sprintf("recipient %d has no report types", i))
+		}
+	}
+
+	if ec.SMTP.Host == "" {
+		errors = append(errors, "SMTP host is empty")
+	}
+	if ec.SMTP.Port == 0 {
+		errors = append(errors, "SMTP port is invalid")
+	}
+	if ec.SMTP.Username == "" {
+		errors = append(errors, "SMTP username is empty")
+	}
+	if ec.SMTP.From == "" {
+		errors = append(errors, "SMTP from address is empty")
+	}
+	switch ec.SMTP.Encryption {
+	case "", "none", "ssl", "starttls":
+	default:
+		errors = append(errors, fmt.Sprintf("SMTP encryption must be one of none, ssl, starttls, got %q", ec.SMTP.Encryption))
+	}
+	if ec.SMTP.Port == 465 && ec.SMTP.Encryption == "none" {
+		errors = append(errors, "SMTP port 465 requires ssl encryption, not none")
+	}
+	if ec.SMTP.Port == 587 && ec.SMTP.Encryption == "ssl" {
+		errors = append(errors, "SMTP port 587 is for starttls, not implicit ssl")
+	}
+
+	if ec.Inbound.Enabled {
+		if ec.Inbound.IMAPHost == "" {
+			errors = append(errors, "inbound.imap_host is empty")
+		}
+		if ec.Inbound.Username == "" {
+			errors = append(errors, "inbound.username is empty")
+		}
+		if ec.Inbound.BounceThreshold <= 0 {
+			errors = append(errors, "inbound.bounce_threshold must be greater than zero")
+		}
+		if len(ec.Inbound.AllowedSenders) == 0 {
+			errors = append(errors, "inbound.allowed_senders must list at least one sender allowed to issue commands")
+		}
+	}
+
+	if len(ec.Reports) == 0 {
+		errors = append(errors, "no reports configured")
+	}
+	for name, report := range ec.Reports {
+		if !report.Enabled || report.Schedule == "" {
+			continue
+		}
+		if _, err := cron.ParseStandard(report.Schedule); err != nil {
+			errors = append(errors, fmt.Sprintf("report %q has invalid cron schedule %q: %v", name, report.Schedule, err))
+		}
+	}
+
+	return errors
+}
+
+// Message is a single outgoing email. HTMLBody and InlineImages are optional;
+// when HTMLBody is empty the message is sent as plain text only.
+type Message struct {
+	To              string
+	Subject         string
+	TextBody        string
+	HTMLBody        string
+	Attachments     []string
+	InlineImages    []string     // embedded via cid: and referenced from HTMLBody as cid:<base filename>
+	FileAttachments []Attachment // in-memory attachments/inline images, as an alternative to the path-based fields above
+}
+
+// Attachment is an in-memory file to include in an email. Unlike Message's
+// path-based Attachments/InlineImages (which read from disk at send time),
+// Attachment carries its bytes directly, for callers that generate content
+// in memory (e.g. a rendered chart) rather than writing it to a file first.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+	Inline      bool   // true embeds the file so it's addressable via ContentID instead of listed as a download
+	ContentID   string // cid referenced from Message.HTMLBody as cid:<ContentID>; defaults to Filename when empty
+}
+
+// Mailer is the interface every email transport implements. EmailService sends
+// all mail through a Mailer so callers can swap SMTP delivery for a dry-run or
+// no-op implementation without touching the report-generation code. Send takes
+// a context so callers can bound how long a single send is allowed to run.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer delivers mail over SMTP using gopkg.in/mail.v2. This is the
+// production Mailer and preserves the dialer behavior EmailService used
+// before the Mailer interface existed.
+type SMTPMailer struct {
+	config *SMTPConfig
+}
+
+// NewSMTPMailer creates a Mailer that dials the configured SMTP server.
+func NewSMTPMailer(config *SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config}
+}
+
+// Send dials the configured SMTP server and delivers the message. When
+// msg.HTMLBody is set, the message is built as multipart/alternative with the
+// plain-text body as the fallback, and any InlineImages are embedded as cid:
+// parts so the HTML body can reference them. ctx bounds how long the dial and
+// send are allowed to take; gopkg.in/mail.v2 has no native context support, so
+// DialAndSend runs on a goroutine and Send returns ctx.Err() if it expires
+// first (the goroutine is left to finish or fail in the background).
+func (sm *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	m := mail.NewMessage()
+	m.SetHeader("From", sm.config.From)
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+	m.SetBody("text/plain", msg.TextBody)
+
+	if msg.HTMLBody != "" {
+		m.AddAlternative("text/html", msg.HTMLBody)
+		for _, imagePath := range msg.InlineImages {
+			if imagePath != "" {
+				m.Embed(imagePath)
+			}
+		}
+	}
+
+	for _, attachmentPath := range msg.Attachments {
+		if attachmentPath != "" {
+			m.Attach(attachmentPath)
+		}
+	}
+
+	for _, att := range msg.FileAttachments {
+		settings := attachmentFileSettings(att)
+		if att.Inline {
+			m.Embed(att.Filename, settings...)
+		} else {
+			m.Attach(att.Filename, settings...)
+		}
+	}
+
+	d := mail.NewDialer(sm.config.Host, sm.config.Port, sm.config.Username, sm.config.Password)
+
+	switch sm.config.Encryption {
+	case "ssl":
+		d.SSL = true
+	case "starttls":
+		d.StartTLSPolicy = mail.MandatoryStartTLS
+	case "none":
+		d.StartTLSPolicy = mail.NoStartTLS
+	}
+
+	if sm.config.InsecureSkipVerify || sm.config.ServerName != "" {
+		d.TLSConfig = &tls.Config{
+			InsecureSkipVerify: sm.config.InsecureSkipVerify,
+			ServerName:         sm.config.ServerName,
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.DialAndSend(m)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// attachmentFileSettings builds the mail.v2 FileSettings needed to attach an
+// in-memory Attachment: a CopyFunc that writes its bytes instead of reading
+// from disk, plus a Content-Type header and, for inline attachments whose
+// ContentID differs from Filename, a Content-ID override.
+func attachmentFileSettings(att Attachment) []mail.FileSetting {
+	settings := []mail.FileSetting{
+		mail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(att.Data)
+			return err
+		}),
+	}
+
+	header := map[string][]string{}
+	if att.ContentType != "" {
+		header["Content-Type"] = []string{att.ContentType}
+	}
+	if att.Inline && att.ContentID != "" && att.ContentID != att.Filename {
+		header["Content-ID"] = []string{fmt.Sprintf("<%s>", att.ContentID)}
+	}
+	if len(header) > 0 {
+		settings = append(settings, mail.SetHeader(header))
+	}
+
+	return settings
+}
+
+// LogMailer logs what would be sent instead of dialing an SMTP server. It's
+// meant for dry runs and local development where no SMTP relay is available.
+type LogMailer struct{}
+
+// NewLogMailer creates a Mailer that only logs outgoing messages.
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the message it would have sent and always succeeds.
+func (lm *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("[dry-run] would send email to %s: subject=%q attachments=%v inline_images=%v file_attachments=%d html=%v\n%s",
+		msg.To, msg.Subject, msg.Attachments, msg.InlineImages, len(msg.FileAttachments), msg.HTMLBody != "", msg.TextBody)
+	return nil
+}
+
+// NullMailer discards every message. It's useful in tests that exercise the
+// report-sending paths without caring whether mail was actually delivered.
+type NullMailer struct{}
+
+// NewNullMailer creates a Mailer that silently discards messages.
+func NewNullMailer() *NullMailer {
+	return &NullMailer{}
+}
+
+// Send discards the message and always succeeds.
+func (nm *NullMailer) Send(ctx context.Context, msg Message) error {
+	return nil
+}
+
+// SelectMailer picks the Mailer to use at runtime. It returns a LogMailer when
+// dryRun is true or the EMAIL_DRY_RUN environment variable is set to "true"
+// or "1", and an SMTPMailer otherwise.
+func SelectMailer(config *SMTPConfig, dryRun bool) Mailer {
+	if dryRun || os.Getenv("EMAIL_DRY_RUN") == "true" || os.Getenv("EMAIL_DRY_RUN") == "1" {
+		return NewLogMailer()
+	}
+	return NewSMTPMailer(config)
+}
+
 // EmailService handles email operations
 type EmailService struct {
 	config *EmailConfig
+	mailer Mailer
 }
 
-// NewEmailService creates a new email service
-func NewEmailService(config *EmailConfig) *EmailService {
+// NewEmailService creates a new email service backed by the given Mailer
+func NewEmailService(config *EmailConfig, mailer Mailer) *EmailService {
 	return &EmailService{
 		config: config,
+		mailer: mailer,
+	}
+}
+
+// buildReportMessage renders the subject/body/HTML templates for a recipient
+// into a ready-to-send Message.
+func (es *EmailService) buildReportMessage(recipient Recipient, reportType string, attachments []string) (Message, error) {
+	subject, err := es.config.FormatEmailSubject(reportType, recipient)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to format email subject: %v", err)
+	}
+
+	body, err := es.config.FormatEmailBody(reportType, recipient)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to format email body: %v", err)
+	}
+
+	htmlBody, err := es.config.FormatEmailHTMLBody(reportType, recipient)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to format email HTML body: %v", err)
 	}
+
+	msg := Message{
+		To:          recipient.Email,
+		Subject:     subject,
+		TextBody:    body,
+		HTMLBody:    htmlBody,
+		Attachments: attachments,
+	}
+	if htmlBody != "" {
+		msg.InlineImages = es.config.Reports[reportType].InlineImages
+	}
+
+	return msg, nil
 }
 
 // SendEmail sends a personalized email to a recipient
 func (es *EmailService) SendEmail(recipient Recipient, reportType string, attachments []string) error {
-	// Format email subject and body
+	msg, err := es.buildReportMessage(recipient, reportType, attachments)
+	if err != nil {
+		return err
+	}
+
+	if err := es.mailer.Send(context.Background(), msg); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully sent %s report to %s (%s)", reportType, recipient.Name, recipient.Email)
+	return nil
+}
+
+// SendReport sends a report to a single recipient with in-memory attachments
+// and inline images, for callers that already have rendered artifacts in
+// memory (e.g. a chart PNG) rather than files written to disk. Use SendEmail
+// for the path-based Settings.Attachments flow instead.
+func (es *EmailService) SendReport(reportType string, recipient Recipient, attachments []Attachment) error {
 	subject, err := es.config.FormatEmailSubject(reportType, recipient)
 	if err != nil {
 		return fmt.Errorf("failed to format email subject: %v", err)
@@ -35,81 +569,238 @@ func (es *EmailService) SendEmail(recipient Recipient, reportType string, attach
 		return fmt.Errorf("failed to format email body: %v", err)
 	}
 
-	// Create message
-	m := mail.NewMessage()
-	m.SetHeader("From", es.config.SMTP.From)
-	m.SetHeader("To", recipient.Email)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/plain", body)
+	htmlBody, err := es.config.FormatEmailHTMLBody(reportType, recipient)
+	if err != nil {
+		return fmt.Errorf("failed to format email HTML body: %v", err)
+	}
 
-	// Add attachments
-	for _, attachmentPath := range attachments {
-		if attachmentPath != "" {
-			m.Attach(attachmentPath)
-		}
+	msg := Message{
+		To:              recipient.Email,
+		Subject:         subject,
+		TextBody:        body,
+		HTMLBody:        htmlBody,
+		FileAttachments: attachments,
+	}
+
+	if err := es.mailer.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("failed to send %s report to %s: %v", reportType, recipient.Email, err)
+	}
+
+	log.Printf("Successfully sent %s report to %s (%s) with %d attachment(s)", reportType, recipient.Name, recipient.Email, len(attachments))
+	return nil
+}
+
+// SendTransactionalReport sends a single ad-hoc report to recipient, as used
+// by the apiserver's POST /api/tx endpoint. templateVars are merged over the
+// default subject/body template variables, letting the caller supply values
+// the YAML-configured templates reference beyond name/date/month/email/dept.
+func (es *EmailService) SendTransactionalReport(reportType string, recipient Recipient, templateVars map[string]interface{}, attachments []Attachment) error {
+	subject, err := es.config.FormatEmailSubject(reportType, recipient, templateVars)
+	if err != nil {
+		return fmt.Errorf("failed to format email subject: %v", err)
 	}
 
-	// Create SMTP dialer
-	port := es.config.SMTP.Port
-	d := mail.NewDialer(es.config.SMTP.Host, port, es.config.SMTP.Username, es.config.SMTP.Password)
+	body, err := es.config.FormatEmailBody(reportType, recipient, templateVars)
+	if err != nil {
+		return fmt.Errorf("failed to format email body: %v", err)
+	}
 
-	// Send email
-	if err := d.DialAndSend(m); err != nil {
-		return fmt.Errorf("failed to send email to %s: %v", recipient.Email, err)
+	htmlBody, err := es.config.FormatEmailHTMLBody(reportType, recipient, templateVars)
+	if err != nil {
+		return fmt.Errorf("failed to format email HTML body: %v", err)
 	}
 
-	log.Printf("Successfully sent %s report to %s (%s)", reportType, recipient.Name, recipient.Email)
+	msg := Message{
+		To:              recipient.Email,
+		Subject:         subject,
+		TextBody:        body,
+		HTMLBody:        htmlBody,
+		FileAttachments: attachments,
+	}
+
+	if err := es.mailer.Send(context.Background(), msg); err != nil {
+		return fmt.Errorf("failed to send transactional %s report to %s: %v", reportType, recipient.Email, err)
+	}
+
+	log.Printf("Successfully sent transactional %s report to %s (%s)", reportType, recipient.Name, recipient.Email)
 	return nil
 }
 
-// SendReportEmails sends reports to all configured recipients for a given report type
-func (es *EmailService) SendReportEmails(reportType string, attachments []string) error {
-	// Get report config
+// isRetriableSendError reports whether err is worth retrying: SMTP responses
+// (4xx transient or 5xx permanent are both treated as retriable here, since a
+// misbehaving relay can return either for the same transient condition),
+// network timeouts, and a deadline expiry from our own send timeout all
+// qualify; context cancellation does not.
+func isRetriableSendError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// sendWithRetry sends msg via mailer, retrying with exponential backoff and
+// jitter on retriable errors until maxAttempts is reached. It returns the
+// number of attempts made and the final error, if any.
+func sendWithRetry(ctx context.Context, mailer Mailer, msg Message, timeout time.Duration, maxAttempts int) (int, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, timeout)
+		lastErr = mailer.Send(sendCtx, msg)
+		cancel()
+
+		if lastErr == nil {
+			return attempt, nil
+		}
+		if attempt == maxAttempts || !isRetriableSendError(lastErr) {
+			return attempt, lastErr
+		}
+
+		backoff := time.Duration(1<<uint(attempt-1)) * 200 * time.Millisecond
+		jitter := time.Duration(rand.Int63n(int64(backoff) / 2))
+		select {
+		case <-time.After(backoff + jitter):
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		}
+	}
+
+	return maxAttempts, lastErr
+}
+
+// SendResult is the outcome of sending a report to a single recipient.
+type SendResult struct {
+	Recipient Recipient
+	Attempts  int
+	Err       error
+}
+
+// Success reports whether the send ultimately succeeded.
+func (r SendResult) Success() bool {
+	return r.Err == nil
+}
+
+// SendReportEmails sends reports to all configured recipients for a given
+// report type. Recipients are processed by a bounded worker pool honoring
+// Settings.Concurrency and Settings.RateLimitPerSecond, retrying transient
+// failures up to Settings.RetryAttempts with backoff, and bounding each
+// attempt by Settings.TimeoutSeconds. It returns a per-recipient SendResult
+// so callers can build a delivery report instead of a single aggregated error.
+func (es *EmailService) SendReportEmails(reportType string, attachments []string) ([]SendResult, error) {
 	reportConfig, exists := es.config.Reports[reportType]
 	if !exists {
-		return fmt.Errorf("report type '%s' not found in config", reportType)
+		return nil, fmt.Errorf("report type '%s' not found in config", reportType)
 	}
 
 	if !reportConfig.Enabled {
 		log.Printf("Report type '%s' is disabled, skipping", reportType)
-		return nil
+		return nil, nil
 	}
 
-	// Get recipients for this report type
 	recipients := es.config.GetRecipientsByReportType(reportType)
 	if len(recipients) == 0 {
 		log.Printf("No recipients configured for report type '%s'", reportType)
-		return nil
+		return nil, nil
 	}
 
-	log.Printf("Sending %s reports to %d recipients", reportType, len(recipients))
+	concurrency := es.config.Settings.Concurrency
+	if concurrency < 1 {
+		concurrency = 5
+	}
+	if concurrency > len(recipients) {
+		concurrency = len(recipients)
+	}
+
+	maxAttempts := es.config.Settings.RetryAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	timeout := time.Duration(es.config.Settings.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	var limiter <-chan time.Time
+	if es.config.Settings.RateLimitPerSecond > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(es.config.Settings.RateLimitPerSecond))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	log.Printf("Sending %s reports to %d recipients (concurrency=%d, retry_attempts=%d)",
+		reportType, len(recipients), concurrency, maxAttempts)
+
+	ctx := context.Background()
+	jobs := make(chan Recipient)
+	resultsCh := make(chan SendResult, len(recipients))
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for recipient := range jobs {
+				if limiter != nil {
+					<-limiter
+				}
+
+				msg, err := es.buildReportMessage(recipient, reportType, attachments)
+				if err != nil {
+					resultsCh <- SendResult{Recipient: recipient, Attempts: 0, Err: err}
+					continue
+				}
+
+				attempts, sendErr := sendWithRetry(ctx, es.mailer, msg, timeout, maxAttempts)
+				if sendErr != nil {
+					log.Printf("Error sending %s report to %s after %d attempt(s): %v", reportType, recipient.Name, attempts, sendErr)
+				}
+				resultsCh <- SendResult{Recipient: recipient, Attempts: attempts, Err: sendErr}
+			}
+		}()
+	}
 
-	// Send email to each recipient
-	var successCount, errorCount int
 	for _, recipient := range recipients {
-		if err := es.SendEmail(recipient, reportType, attachments); err != nil {
-			log.Printf("Error sending %s report to %s: %v", reportType, recipient.Name, err)
-			errorCount++
-		} else {
+		jobs <- recipient
+	}
+	close(jobs)
+
+	wg.Wait()
+	close(resultsCh)
+
+	results := make([]SendResult, 0, len(recipients))
+	var successCount, errorCount int
+	for result := range resultsCh {
+		if result.Success() {
 			successCount++
+		} else {
+			errorCount++
 		}
-
-		// Small delay between emails to avoid overwhelming the SMTP server
-		time.Sleep(500 * time.Millisecond)
+		results = append(results, result)
 	}
 
 	log.Printf("Completed sending %s reports: %d successful, %d failed", reportType, successCount, errorCount)
 
 	if errorCount > 0 {
-		return fmt.Errorf("failed to send %d of %d emails", errorCount, len(recipients))
+		return results, fmt.Errorf("failed to send %d of %d emails", errorCount, len(recipients))
 	}
 
-	return nil
+	return results, nil
 }
 
 // SendTestEmail sends a test email to verify SMTP configuration
 func (es *EmailService) SendTestEmail(toEmail string) error {
-	// Create a temporary recipient for testing
 	testRecipient := Recipient{
 		Name:  "Test User",
 		Email: toEmail,
@@ -125,19 +816,12 @@ func (es *EmailService) SendTestEmail(toEmail string) error {
 		return fmt.Errorf("failed to format test email body: %v", err)
 	}
 
-	// Create message
-	m := mail.NewMessage()
-	m.SetHeader("From", es.config.SMTP.From)
-	m.SetHeader("To", toEmail)
-	m.SetHeader("Subject", fmt.Sprintf("[TEST] %s", subject))
-	m.SetBody("text/plain", fmt.Sprintf("This is a test email.\n\n%s", body))
-
-	// Create SMTP dialer
-	port := es.config.SMTP.Port
-	d := mail.NewDialer(es.config.SMTP.Host, port, es.config.SMTP.Username, es.config.SMTP.Password)
-
-	// Send email
-	if err := d.DialAndSend(m); err != nil {
+	msg := Message{
+		To:       toEmail,
+		Subject:  fmt.Sprintf("[TEST] %s", subject),
+		TextBody: fmt.Sprintf("This is a test email.\n\n%s", body),
+	}
+	if err := es.mailer.Send(context.Background(), msg); err != nil {
 		return fmt.Errorf("failed to send test email: %v", err)
 	}
 
@@ -147,19 +831,16 @@ func (es *EmailService) SendTestEmail(toEmail string) error {
 
 // SendPersonalizedEmail sends an email with custom subject and body to a recipient
 func (es *EmailService) SendPersonalizedEmail(recipient Recipient, subjectTemplate, bodyTemplate string, data map[string]interface{}) error {
-	// Merge recipient data with custom data
 	templateData := make(map[string]interface{})
 	for k, v := range data {
 		templateData[k] = v
 	}
-	
-	// Add recipient-specific data
+
 	templateData["name"] = recipient.Name
 	templateData["email"] = recipient.Email
 	templateData["date"] = time.Now().Format("2006-01-02")
 	templateData["month"] = time.Now().Format("January 2006")
 
-	// Format subject and body
 	subject, err := executeTemplate(subjectTemplate, templateData)
 	if err != nil {
 		return fmt.Errorf("failed to format email subject: %v", err)
@@ -170,19 +851,12 @@ func (es *EmailService) SendPersonalizedEmail(recipient Recipient, subjectTempla
 		return fmt.Errorf("failed to format email body: %v", err)
 	}
 
-	// Create message
-	m := mail.NewMessage()
-	m.SetHeader("From", es.config.SMTP.From)
-	m.SetHeader("To", recipient.Email)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/plain", body)
-
-	// Create SMTP dialer
-	port := es.config.SMTP.Port
-	d := mail.NewDialer(es.config.SMTP.Host, port, es.config.SMTP.Username, es.config.SMTP.Password)
-
-	// Send email
-	if err := d.DialAndSend(m); err != nil {
+	msg := Message{
+		To:       recipient.Email,
+		Subject:  subject,
+		TextBody: body,
+	}
+	if err := es.mailer.Send(context.Background(), msg); err != nil {
 		return fmt.Errorf("failed to send personalized email to %s: %v", recipient.Email, err)
 	}
 
@@ -205,24 +879,6 @@ func executeTemplate(tmplStr string, data map[string]interface{}) (string, error
 	return buf.String(), nil
 }
 
-// ScheduleReportSending schedules report sending based on cron-like expressions
-// This is a simplified version - in production, you'd use a proper scheduler
-func (es *EmailService) ScheduleReportSending(reportType string, attachments []string) error {
-	reportConfig, exists := es.config.Reports[reportType]
-	if !exists {
-		return fmt.Errorf("report type '%s' not found in config", reportType)
-	}
-
-	if !reportConfig.Enabled {
-		log.Printf("Report type '%s' is disabled, not scheduling", reportType)
-		return nil
-	}
-
-	log.Printf("Scheduling %s report with schedule: %s", reportType, reportConfig.Schedule)
-	
-	// In a real implementation, you would parse the cron expression and schedule
-	// the task using a scheduler library like robfig/cron
-	// For now, we'll just log that the report is scheduled
-	
-	return nil
-}
\ No newline at end of file
+// Scheduling of report sends now lives in the Scheduler type (see scheduler.go),
+// which runs real cron jobs via github.com/robfig/cron/v3 instead of logging
+// a stub.
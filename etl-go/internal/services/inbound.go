@@ -0,0 +1,398 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"strings"
+	"sync"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// InboundMailer ingests replies sent to the reporting mailbox: subscription
+// commands ("subscribe weekly", "unsubscribe monthly", "run now sales_daily")
+// and bounce/DSN reports. It can either poll IMAP or be mounted as an HTTP
+// webhook handler that accepts raw MIME bodies.
+type InboundMailer struct {
+	config     *EmailConfig
+	configPath string
+	runNow     func(reportType string) error
+
+	mu       sync.Mutex
+	failures map[string]int // recipient email -> bounces seen by this InboundMailer; never reset
+}
+
+// NewInboundMailer creates an InboundMailer. configPath is where the
+// EmailConfig was loaded from and subscription changes are persisted back
+// to. runNow, if non-nil, is invoked for a "run now <report>" command.
+func NewInboundMailer(config *EmailConfig, configPath string, runNow func(reportType string) error) *InboundMailer {
+	return &InboundMailer{
+		config:     config,
+		configPath: configPath,
+		runNow:     runNow,
+		failures:   make(map[string]int),
+	}
+}
+
+// isAllowedSender reports whether from may issue subscription commands.
+func (im *InboundMailer) isAllowedSender(from string) bool {
+	from = strings.ToLower(strings.TrimSpace(from))
+	for _, allowed := range im.config.Inbound.AllowedSenders {
+		if strings.ToLower(strings.TrimSpace(allowed)) == from {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCommand recognizes "subscribe <type>", "unsubscribe <type>", and
+// "run now <report>" (case-insensitive, leading/trailing whitespace ignored).
+func parseCommand(body string) (verb string, arg string, ok bool) {
+	line := strings.ToLower(strings.TrimSpace(firstLine(body)))
+	switch {
+	case strings.HasPrefix(line, "subscribe "):
+		return "subscribe", strings.TrimSpace(line[len("subscribe "):]), true
+	case strings.HasPrefix(line, "unsubscribe "):
+		return "unsubscribe", strings.TrimSpace(line[len("unsubscribe "):]), true
+	case strings.HasPrefix(line, "run now "):
+		return "run now", strings.TrimSpace(line[len("run now "):]), true
+	default:
+		return "", "", false
+	}
+}
+
+// firstLine returns the first non-empty line of body, which is where a reply
+// places its command when replying above the quoted original message.
+func firstLine(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
+
+// HandleCommandEmail applies a subscribe/unsubscribe/run-now command found in
+// an email body sent by from, persisting any Recipients change to configPath.
+// from is a raw "From:" header value (e.g. "Alice <alice@example.com>"), not
+// a bare address; it's parsed down to the address before the allow-list
+// check and before it's used as a Recipient key.
+func (im *InboundMailer) HandleCommandEmail(from, body string) error {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("failed to parse From header %q: %v", from, err)
+	}
+	from = addr.Address
+
+	if !im.isAllowedSender(from) {
+		return fmt.Errorf("sender %s is not in inbound.allowed_senders, ignoring command", from)
+	}
+
+	verb, arg, ok := parseCommand(body)
+	if !ok {
+		return fmt.Errorf("no recognized command in message from %s", from)
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	switch verb {
+	case "subscribe":
+		im.addReportType(from, arg)
+		return SaveEmailConfig(im.configPath, im.config)
+	case "unsubscribe":
+		im.removeReportType(from, arg)
+		return SaveEmailConfig(im.configPath, im.config)
+	case "run now":
+		if im.runNow == nil {
+			return fmt.Errorf("run now %q requested but no runner is configured", arg)
+		}
+		return im.runNow(arg)
+	default:
+		return fmt.Errorf("unrecognized command %q", verb)
+	}
+}
+
+// addReportType subscribes email to reportType, creating a Recipient entry
+// if one doesn't already exist.
+func (im *InboundMailer) addReportType(email, reportType string) {
+	for i, recipient := range im.config.Recipients {
+		if strings.EqualFold(recipient.Email, email) {
+			for _, rt := range recipient.ReportTypes {
+				if rt == reportType {
+					return
+				}
+			}
+			im.config.Recipients[i].ReportTypes = append(recipient.ReportTypes, reportType)
+			return
+		}
+	}
+	im.config.Recipients = append(im.config.Recipients, Recipient{
+		Email:       email,
+		ReportTypes: []string{reportType},
+	})
+}
+
+// removeReportType unsubscribes email from reportType. The Recipient entry
+// itself is left in place (with zero report types) so re-subscribing doesn't
+// lose the recipient's name/department.
+func (im *InboundMailer) removeReportType(email, reportType string) {
+	for i, recipient := range im.config.Recipients {
+		if !strings.EqualFold(recipient.Email, email) {
+			continue
+		}
+		var kept []string
+		for _, rt := range recipient.ReportTypes {
+			if rt != reportType {
+				kept = append(kept, rt)
+			}
+		}
+		im.config.Recipients[i].ReportTypes = kept
+		return
+	}
+}
+
+// HandleBounce parses a bounce/DSN message (a multipart/report per RFC 3464)
+// and increments the offending recipient's failure count, auto-disabling it
+// once Inbound.BounceThreshold is reached. It returns an admin digest line
+// describing what happened.
+func (im *InboundMailer) HandleBounce(r io.Reader) (string, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse bounce message: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse bounce content type: %v", err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return "", fmt.Errorf("bounce message is not multipart (got %s)", mediaType)
+	}
+
+	recipient, err := extractFinalRecipient(multipart.NewReader(msg.Body, params["boundary"]))
+	if err != nil {
+		return "", err
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.failures[recipient]++
+	count := im.failures[recipient]
+
+	if count < im.config.Inbound.BounceThreshold {
+		return fmt.Sprintf("bounce %d/%d recorded for %s", count, im.config.Inbound.BounceThreshold, recipient), nil
+	}
+
+	im.disableRecipient(recipient)
+	if err := SaveEmailConfig(im.configPath, im.config); err != nil {
+		return "", fmt.Errorf("recipient %s disabled after %d bounces, but failed to persist config: %v", recipient, count, err)
+	}
+
+	return fmt.Sprintf("recipient %s auto-disabled after %d bounces", recipient, count), nil
+}
+
+// extractFinalRecipient scans a multipart/report body for the
+// message/delivery-status part and returns its Final-Recipient address.
+func extractFinalRecipient(reader *multipart.Reader) (string, error) {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read bounce part: %v", err)
+		}
+
+		if !strings.HasPrefix(part.Header.Get("Content-Type"), "message/delivery-status") {
+			continue
+		}
+
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return "", fmt.Errorf("failed to read delivery-status part: %v", err)
+		}
+
+		for _, line := range strings.Split(string(body), "\n") {
+			if strings.HasPrefix(strings.ToLower(line), "final-recipient:") {
+				fields := strings.SplitN(line, ";", 2)
+				if len(fields) == 2 {
+					return strings.TrimSpace(fields[1]), nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("no Final-Recipient found in delivery-status report")
+}
+
+// disableRecipient removes every report subscription for email so future
+// SendReportEmails runs skip it, without deleting the Recipient record.
+func (im *InboundMailer) disableRecipient(email string) {
+	for i, recipient := range im.config.Recipients {
+		if strings.EqualFold(recipient.Email, email) {
+			im.config.Recipients[i].ReportTypes = nil
+			log.Printf("Auto-disabled recipient %s after repeated bounces", email)
+			return
+		}
+	}
+}
+
+// ServeHTTP accepts a raw MIME email as a webhook POST body (the JSON/form
+// wrapping used by common inbound-email providers is intentionally not
+// parsed here; a thin adapter in front of this handler can unwrap that and
+// forward the raw message). Bounce/DSN reports and command emails are both
+// handled; a plain 200 is returned once the message has been processed.
+func (im *InboundMailer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(body)))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse message: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if isBounceMessage(msg) {
+		digest, err := im.HandleBounce(strings.NewReader(string(body)))
+		if err != nil {
+			log.Printf("Failed to process bounce webhook: %v", err)
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		log.Printf("Inbound bounce digest: %s", digest)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	text, err := io.ReadAll(msg.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read message body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := im.HandleCommandEmail(msg.Header.Get("From"), string(text)); err != nil {
+		log.Printf("Failed to process command webhook: %v", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// isBounceMessage heuristically identifies a DSN by its Content-Type.
+func isBounceMessage(msg *mail.Message) bool {
+	mediaType, _, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "multipart/report"
+}
+
+// PollIMAP connects to Inbound.IMAPHost, logs in, and processes every unseen
+// message in Inbound.Mailbox (defaulting to INBOX) as either a bounce or a
+// command email, marking each as seen once handled. It's meant to be called
+// on an interval (e.g. from the Scheduler's cron) rather than run as a
+// long-lived IDLE loop.
+func (im *InboundMailer) PollIMAP() error {
+	addr := fmt.Sprintf("%s:%d", im.config.Inbound.IMAPHost, im.config.Inbound.IMAPPort)
+	c, err := client.DialTLS(addr, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to IMAP server %s: %v", addr, err)
+	}
+	defer c.Logout()
+
+	if err := c.Login(im.config.Inbound.Username, im.config.Inbound.Password); err != nil {
+		return fmt.Errorf("failed to log in to IMAP server: %v", err)
+	}
+
+	mailbox := im.config.Inbound.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	if _, err := c.Select(mailbox, false); err != nil {
+		return fmt.Errorf("failed to select mailbox %s: %v", mailbox, err)
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	seqNums, err := c.Search(criteria)
+	if err != nil {
+		return fmt.Errorf("failed to search for unseen messages: %v", err)
+	}
+	if len(seqNums) == 0 {
+		return nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(seqNums...)
+
+	messages := make(chan *imap.Message, len(seqNums))
+	section := &imap.BodySectionName{}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Fetch(seqSet, []imap.FetchItem{section.FetchItem()}, messages)
+	}()
+
+	for msg := range messages {
+		literal := msg.GetBody(section)
+		if literal == nil {
+			continue
+		}
+		raw, err := io.ReadAll(literal)
+		if err != nil {
+			log.Printf("Failed to read IMAP message body: %v", err)
+			continue
+		}
+
+		parsed, err := mail.ReadMessage(strings.NewReader(string(raw)))
+		if err != nil {
+			log.Printf("Failed to parse IMAP message: %v", err)
+			continue
+		}
+
+		if isBounceMessage(parsed) {
+			if digest, err := im.HandleBounce(strings.NewReader(string(raw))); err != nil {
+				log.Printf("Failed to process bounce: %v", err)
+			} else {
+				log.Printf("Inbound bounce digest: %s", digest)
+			}
+			continue
+		}
+
+		text, err := io.ReadAll(parsed.Body)
+		if err != nil {
+			log.Printf("Failed to read command message body: %v", err)
+			continue
+		}
+		if err := im.HandleCommandEmail(parsed.Header.Get("From"), string(text)); err != nil {
+			log.Printf("Failed to process command email: %v", err)
+		}
+	}
+
+	if err := <-done; err != nil {
+		return fmt.Errorf("IMAP fetch failed: %v", err)
+	}
+
+	markSeenSet := new(imap.SeqSet)
+	markSeenSet.AddNum(seqNums...)
+	flagItem := imap.FormatFlagsOp(imap.AddFlags, true)
+	if err := c.Store(markSeenSet, flagItem, []interface{}{imap.SeenFlag}, nil); err != nil {
+		return fmt.Errorf("failed to mark messages seen: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// RunETLFunc runs whatever extract/transform/load a report's ReportConfig
+// needs (a SQL pull or a file import) to produce fresh data before the report
+// is mailed out. It's supplied by the caller so Scheduler doesn't need to
+// depend on a concrete ETLService type.
+type RunETLFunc func(reportType string, report ReportConfig) error
+
+// Scheduler runs each enabled report's ETL-and-mail pipeline on its
+// configured cron schedule.
+type Scheduler struct {
+	cron         *cron.Cron
+	config       *EmailConfig
+	emailService *EmailService
+	runETL       RunETLFunc
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+	entries map[string]cron.EntryID
+}
+
+// NewScheduler creates a Scheduler that runs runETL followed by
+// emailService.SendReportEmails for each enabled, scheduled report.
+// EmailConfig.Settings.Timezone selects the cron instance's location;
+// an empty timezone means UTC, and an invalid one is returned as an error.
+func NewScheduler(config *EmailConfig, emailService *EmailService, runETL RunETLFunc) (*Scheduler, error) {
+	loc := time.UTC
+	if config.Settings.Timezone != "" {
+		parsedLoc, err := time.LoadLocation(config.Settings.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %v", config.Settings.Timezone, err)
+		}
+		loc = parsedLoc
+	}
+
+	return &Scheduler{
+		cron:         cron.New(cron.WithLocation(loc)),
+		config:       config,
+		emailService: emailService,
+		runETL:       runETL,
+		lastRun:      make(map[string]time.Time),
+		entries:      make(map[string]cron.EntryID),
+	}, nil
+}
+
+// Start registers a cron job for every enabled report with a non-empty
+// schedule and begins running them in the background.
+func (s *Scheduler) Start() error {
+	for reportType, report := range s.config.Reports {
+		if !report.Enabled || report.Schedule == "" {
+			continue
+		}
+
+		reportType, report := reportType, report // capture for the closure
+		entryID, err := s.cron.AddFunc(report.Schedule, func() {
+			s.runPipeline(reportType, report)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule report %q: %v", reportType, err)
+		}
+
+		s.entries[reportType] = entryID
+		log.Printf("Scheduled %s report with cron expression %q", reportType, report.Schedule)
+	}
+
+	s.cron.Start()
+	return nil
+}
+
+// Stop stops the cron scheduler and waits for any running job to finish.
+func (s *Scheduler) Stop() {
+	ctx := s.cron.Stop()
+	<-ctx.Done()
+}
+
+// RunNow triggers a report's pipeline immediately, bypassing its cron
+// schedule. It's used to back a --run-now CLI flag for manual triggers.
+func (s *Scheduler) RunNow(reportType string) error {
+	report, exists := s.config.Reports[reportType]
+	if !exists {
+		return fmt.Errorf("report type '%s' not found in config", reportType)
+	}
+	s.runPipeline(reportType, report)
+	return nil
+}
+
+// LastRun returns when a report's pipeline last ran and whether it has run at all.
+func (s *Scheduler) LastRun(reportType string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lastRun[reportType]
+	return t, ok
+}
+
+// runPipeline runs the ETL step and then mails the report, recording the run
+// time regardless of outcome so LastRun reflects attempts, not just successes.
+func (s *Scheduler) runPipeline(reportType string, report ReportConfig) {
+	s.mu.Lock()
+	s.lastRun[reportType] = time.Now()
+	s.mu.Unlock()
+
+	if s.runETL != nil {
+		if err := s.runETL(reportType, report); err != nil {
+			log.Printf("ETL run for report %q failed, sending with existing attachments: %v", reportType, err)
+		}
+	}
+
+	if _, err := s.emailService.SendReportEmails(reportType, s.config.Settings.Attachments); err != nil {
+		log.Printf("Failed to send report %q: %v", reportType, err)
+	}
+}
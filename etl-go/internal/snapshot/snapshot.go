@@ -0,0 +1,307 @@
+// Package snapshot backs "as of" time-travel queries with a real
+// MVCC-style history table per source table, rather than a simulated
+// clock. The main ETL write path (see ETLService.upsertBatch) appends a
+// version via AppendVersion alongside every upsert, so a SnapshotStore can
+// later reconstruct any table as it stood at a past instant; cmd/time-travel
+// is the CLI surface for reading that history back out.
+package snapshot
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// asOfQueryRe matches a "SELECT <cols> FROM <table> AS OF TIMESTAMP '<ts>'"
+// query, capturing the select-list, table name, and timestamp literal so
+// RewriteAsOfQuery can turn it into a query against __history_<table>.
+var asOfQueryRe = regexp.MustCompile(`(?is)^SELECT\s+(.+?)\s+FROM\s+(\w+)\s+AS\s+OF\s+TIMESTAMP\s+'([^']+)'(.*)$`)
+
+// SnapshotStore backs TimeTravelService's "as-of" queries with a real
+// MVCC-style history table per source table, rather than the simulated
+// clock SetTime/AdvanceTime print. Every row version a caller appends via
+// AppendVersion gets its own (pk, tx_start_ts, tx_end_ts, row_json) tuple
+// in __history_<table>; an "as of" read is a bounded range scan over that
+// table instead of a live snapshot read.
+type SnapshotStore struct {
+	db *sql.DB
+}
+
+// NewSnapshotStore connects to the database SnapshotStore's history tables
+// live in.
+func NewSnapshotStore(dbHost, dbName, dbUser, dbPassword string) (*SnapshotStore, error) {
+	psqlInfo := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=disable",
+		dbHost, dbUser, dbPassword, dbName)
+	db, err := sql.Open("postgres", psqlInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping PostgreSQL: %v", err)
+	}
+	return &SnapshotStore{db: db}, nil
+}
+
+// NewSnapshotStoreFromDB wraps an already-open db, for callers (like
+// ETLService) that write history alongside data they're already connected
+// to, rather than dialing a second connection to the same database.
+func NewSnapshotStoreFromDB(db *sql.DB) *SnapshotStore {
+	return &SnapshotStore{db: db}
+}
+
+// Close closes the underlying database connection. Callers that built the
+// store via NewSnapshotStoreFromDB own db's lifecycle and should not call
+// Close.
+func (s *SnapshotStore) Close() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+// historyTable returns the quoted __history_<table> identifier for table,
+// so a table name originating from a CLI flag or rewritten query can't
+// break out of the generated SQL.
+func historyTable(table string) string {
+	return quoteIdent("__history_" + table)
+}
+
+// quoteIdent double-quotes a Postgres identifier, escaping any embedded
+// double quotes.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// ensureHistoryTable creates table's history table if it doesn't already
+// exist.
+func (s *SnapshotStore) ensureHistoryTable(table string) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id          BIGSERIAL PRIMARY KEY,
+			pk          TEXT NOT NULL,
+			tx_start_ts TIMESTAMPTZ NOT NULL DEFAULT now(),
+			tx_end_ts   TIMESTAMPTZ,
+			row_json    JSONB NOT NULL
+		)
+	`, historyTable(table))
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create history table for %s: %v", table, err)
+	}
+	return nil
+}
+
+// AppendVersion records a new version of the row identified by pk in
+// table's history: it closes out whatever version was previously open
+// (tx_end_ts IS NULL) for pk, then inserts row as the new open version.
+// ETL writers call this alongside their normal write so later "as of"
+// reads can reconstruct the row as it stood at any past instant.
+func (s *SnapshotStore) AppendVersion(table, pk string, row map[string]interface{}) error {
+	if err := s.ensureHistoryTable(table); err != nil {
+		return err
+	}
+
+	rowJSON, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Errorf("failed to marshal row for %s pk %s: %v", table, pk, err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	closeQuery := fmt.Sprintf(`UPDATE %s SET tx_end_ts = now() WHERE pk = $1 AND tx_end_ts IS NULL`, historyTable(table))
+	if _, err := tx.Exec(closeQuery, pk); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to close prior version of %s pk %s: %v", table, pk, err)
+	}
+
+	insertQuery := fmt.Sprintf(`INSERT INTO %s (pk, row_json) VALUES ($1, $2)`, historyTable(table))
+	if _, err := tx.Exec(insertQuery, pk, rowJSON); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert new version of %s pk %s: %v", table, pk, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit version of %s pk %s: %v", table, pk, err)
+	}
+	return nil
+}
+
+// SelectAsOf reconstructs table as it stood at asOf: every pk whose
+// version's [tx_start_ts, tx_end_ts) range contains asOf. Rows deleted
+// before asOf (closed with no later version) are correctly omitted. Each
+// returned row carries its history pk under the "pk" key alongside
+// row_json's unmarshaled fields, so callers like Diff can index rows
+// without relying on the original data having its own "id"/"pk" column.
+func (s *SnapshotStore) SelectAsOf(table string, asOf time.Time) ([]map[string]interface{}, error) {
+	if err := s.ensureHistoryTable(table); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT pk, row_json FROM %s
+		WHERE tx_start_ts <= $1 AND (tx_end_ts IS NULL OR tx_end_ts > $1)
+		ORDER BY pk
+	`, historyTable(table))
+	rows, err := s.db.Query(query, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select %s as of %s: %v", table, asOf, err)
+	}
+	defer rows.Close()
+
+	var result []map[string]interface{}
+	for rows.Next() {
+		var pk string
+		var rowJSON []byte
+		if err := rows.Scan(&pk, &rowJSON); err != nil {
+			return nil, err
+		}
+		var row map[string]interface{}
+		if err := json.Unmarshal(rowJSON, &row); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal history row for %s: %v", table, err)
+		}
+		row["pk"] = pk
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// RewriteAsOfQuery rewrites a "SELECT ... FROM t AS OF TIMESTAMP '...'"
+// query into a plain query against t's history table bounded to that
+// timestamp, plus the parsed timestamp. Callers run the returned query
+// with the returned time bound as its one placeholder argument.
+//
+// The rewritten FROM clause is a subquery that only exposes t's history
+// row as the single JSONB column row_json — RewriteAsOfQuery does no
+// catalog lookup, so it has no way to know t's real column names/types
+// and project row_json back into them. The select-list must therefore be
+// exactly "row_json" (optionally qualified as "t.row_json"); anything
+// else, including "SELECT *" or real column names, is rejected rather
+// than silently rewritten into a query that fails at execution with
+// "column does not exist". Use SelectAsOf for typed, column-aware as-of
+// reads.
+func RewriteAsOfQuery(query string) (rewritten string, asOf time.Time, err error) {
+	match := asOfQueryRe.FindStringSubmatch(strings.TrimSpace(query))
+	if match == nil {
+		return "", time.Time{}, fmt.Errorf("query does not match the 'FROM <table> AS OF TIMESTAMP' form: %s", query)
+	}
+
+	selectList, table, tsLiteral, rest := match[1], match[2], match[3], match[4]
+	trimmedSelectList := strings.TrimSpace(selectList)
+	if !strings.EqualFold(trimmedSelectList, "row_json") && !strings.EqualFold(trimmedSelectList, table+".row_json") {
+		return "", time.Time{}, fmt.Errorf(
+			"AS OF TIMESTAMP queries may only select row_json (got %q); the history subquery has no other columns to project", trimmedSelectList)
+	}
+
+	asOf, err = time.Parse("2006-01-02 15:04:05", tsLiteral)
+	if err != nil {
+		asOf, err = time.Parse(time.RFC3339, tsLiteral)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("invalid AS OF TIMESTAMP %q: %v", tsLiteral, err)
+		}
+	}
+
+	historyAlias := fmt.Sprintf(`(SELECT row_json FROM %s WHERE tx_start_ts <= $1 AND (tx_end_ts IS NULL OR tx_end_ts > $1)) AS %s`,
+		historyTable(table), table)
+	rewritten = fmt.Sprintf("SELECT %s FROM %s%s", selectList, historyAlias, rest)
+	return rewritten, asOf, nil
+}
+
+// Diff returns, per pk, the row versions in table that differ between from
+// and to, keyed by pk with a [from-value, to-value] pair (either may be nil
+// if the row didn't exist as of that time).
+func (s *SnapshotStore) Diff(table string, from, to time.Time) (map[string][2]map[string]interface{}, error) {
+	fromRows, err := s.SelectAsOf(table, from)
+	if err != nil {
+		return nil, err
+	}
+	toRows, err := s.SelectAsOf(table, to)
+	if err != nil {
+		return nil, err
+	}
+
+	index := func(rows []map[string]interface{}) map[string]map[string]interface{} {
+		m := make(map[string]map[string]interface{}, len(rows))
+		for _, row := range rows {
+			if pk, ok := row["pk"].(string); ok {
+				m[pk] = row
+			} else if id, ok := row["id"]; ok {
+				m[fmt.Sprintf("%v", id)] = row
+			}
+		}
+		return m
+	}
+
+	fromIndex, toIndex := index(fromRows), index(toRows)
+	diff := make(map[string][2]map[string]interface{})
+	for pk, fromRow := range fromIndex {
+		toRow := toIndex[pk]
+		if !rowsEqual(fromRow, toRow) {
+			diff[pk] = [2]map[string]interface{}{fromRow, toRow}
+		}
+	}
+	for pk, toRow := range toIndex {
+		if _, seen := fromIndex[pk]; !seen {
+			diff[pk] = [2]map[string]interface{}{nil, toRow}
+		}
+	}
+	return diff, nil
+}
+
+// rowsEqual compares two history rows for equality via their canonical
+// JSON encoding, since map key order is otherwise unstable.
+func rowsEqual(a, b map[string]interface{}) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return string(aJSON) == string(bJSON)
+}
+
+// RestoreTable reconstructs table as it stood at asOf into a new table
+// newTable, for recovering a dropped or truncated table. The new table is
+// plain JSONB storage (one row_json column per reconstructed row) rather
+// than table's original typed schema, since the history table itself only
+// ever recorded JSON.
+func (s *SnapshotStore) RestoreTable(table, newTable string, asOf time.Time) (int, error) {
+	rows, err := s.SelectAsOf(table, asOf)
+	if err != nil {
+		return 0, err
+	}
+
+	createQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (row_json JSONB NOT NULL)`, quoteIdent(newTable))
+	if _, err := s.db.Exec(createQuery); err != nil {
+		return 0, fmt.Errorf("failed to create restore target %s: %v", newTable, err)
+	}
+
+	insertQuery := fmt.Sprintf(`INSERT INTO %s (row_json) VALUES ($1)`, quoteIdent(newTable))
+	for _, row := range rows {
+		rowJSON, err := json.Marshal(row)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal row for restore: %v", err)
+		}
+		if _, err := s.db.Exec(insertQuery, rowJSON); err != nil {
+			return 0, fmt.Errorf("failed to insert restored row into %s: %v", newTable, err)
+		}
+	}
+	return len(rows), nil
+}
+
+// PruneHistory deletes history rows for table that closed (tx_end_ts IS NOT
+// NULL) before the retention cutoff, so __history_<table> doesn't grow
+// without bound. It never removes a row's currently-open version.
+func (s *SnapshotStore) PruneHistory(table string, retention time.Duration) (int64, error) {
+	if err := s.ensureHistoryTable(table); err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-retention)
+	query := fmt.Sprintf(`DELETE FROM %s WHERE tx_end_ts IS NOT NULL AND tx_end_ts < $1`, historyTable(table))
+	result, err := s.db.Exec(query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune history for %s: %v", table, err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,29 @@
+package transform
+
+// config is the format-independent representation both the HCL and YAML
+// loaders build, ready for Compile. Column order is preserved from the
+// file, since Compile applies each column's steps in that order.
+type config struct {
+	LookupTables map[string]map[string]string
+	Columns      []columnConfig
+}
+
+// columnConfig holds every operation declared for one column. A zero value
+// field (empty string, false, nil) means that operation wasn't configured
+// for this column.
+type columnConfig struct {
+	Name         string
+	Rename       string
+	Cast         string
+	Trim         bool
+	RegexReplace *regexReplaceConfig
+	Hash         string
+	Lookup       string
+	Derive       string
+	DropIf       string
+}
+
+type regexReplaceConfig struct {
+	Pattern     string
+	Replacement string
+}
@@ -0,0 +1,93 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+)
+
+// hclConfig is the HCL on-disk schema: a repeated "column" block labeled by
+// column name, and a repeated "lookup_table" block labeled by table name,
+// e.g.:
+//
+//	lookup_table "country_names" {
+//	  values = {
+//	    US = "United States"
+//	    CA = "Canada"
+//	  }
+//	}
+//
+//	column "user_email" {
+//	  hash = "sha256"
+//	}
+//
+//	column "country" {
+//	  lookup = "country_names"
+//	}
+type hclConfig struct {
+	Columns      []hclColumn      `hcl:"column,block"`
+	LookupTables []hclLookupTable `hcl:"lookup_table,block"`
+}
+
+type hclColumn struct {
+	Name         string           `hcl:"name,label"`
+	Rename       *string          `hcl:"rename"`
+	Cast         *string          `hcl:"cast"`
+	Trim         *bool            `hcl:"trim"`
+	RegexReplace *hclRegexReplace `hcl:"regex_replace,block"`
+	Hash         *string          `hcl:"hash"`
+	Lookup       *string          `hcl:"lookup"`
+	Derive       *string          `hcl:"derive"`
+	DropIf       *string          `hcl:"drop_if"`
+}
+
+type hclRegexReplace struct {
+	Pattern     string `hcl:"pattern"`
+	Replacement string `hcl:"replacement"`
+}
+
+type hclLookupTable struct {
+	Name   string            `hcl:"name,label"`
+	Values map[string]string `hcl:"values"`
+}
+
+func loadHCLConfig(path string) (*config, error) {
+	var parsed hclConfig
+	if err := hclsimple.DecodeFile(path, nil, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse transform config %s: %v", path, err)
+	}
+
+	cfg := &config{LookupTables: make(map[string]map[string]string)}
+	for _, lt := range parsed.LookupTables {
+		cfg.LookupTables[lt.Name] = lt.Values
+	}
+	for _, col := range parsed.Columns {
+		c := columnConfig{Name: col.Name}
+		if col.Rename != nil {
+			c.Rename = *col.Rename
+		}
+		if col.Cast != nil {
+			c.Cast = *col.Cast
+		}
+		if col.Trim != nil {
+			c.Trim = *col.Trim
+		}
+		if col.Hash != nil {
+			c.Hash = *col.Hash
+		}
+		if col.Lookup != nil {
+			c.Lookup = *col.Lookup
+		}
+		if col.Derive != nil {
+			c.Derive = *col.Derive
+		}
+		if col.DropIf != nil {
+			c.DropIf = *col.DropIf
+		}
+		if col.RegexReplace != nil {
+			c.RegexReplace = &regexReplaceConfig{Pattern: col.RegexReplace.Pattern, Replacement: col.RegexReplace.Replacement}
+		}
+		cfg.Columns = append(cfg.Columns, c)
+	}
+	return cfg, nil
+}
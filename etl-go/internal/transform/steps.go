@@ -0,0 +1,271 @@
+package transform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// Step is one compiled operation in a Pipeline, scoped to a single column
+// except dropIfStep, which can end the whole record.
+type Step interface {
+	// Apply applies the step to record in place, returning false if the
+	// record should be dropped entirely.
+	Apply(record Record) (bool, error)
+}
+
+// Compile turns cfg into an ordered Pipeline: for every configured column,
+// in file order, each operation present on that column becomes its own
+// Step, always in the fixed order rename, cast, trim, regex_replace, hash,
+// lookup, derive, drop_if, so later steps on a column see the column under
+// its new name and already cast/cleaned value.
+func Compile(cfg *config) (*Pipeline, error) {
+	p := &Pipeline{}
+	for _, col := range cfg.Columns {
+		if col.Name == "" {
+			return nil, fmt.Errorf("transform config has a column entry with no name")
+		}
+
+		targetName := col.Name
+		if col.Rename != "" {
+			p.steps = append(p.steps, renameStep{from: col.Name, to: col.Rename})
+			targetName = col.Rename
+		}
+
+		if col.Cast != "" {
+			p.steps = append(p.steps, castStep{column: targetName, to: col.Cast})
+		}
+		if col.Trim {
+			p.steps = append(p.steps, trimStep{column: targetName})
+		}
+		if col.RegexReplace != nil {
+			re, err := regexp.Compile(col.RegexReplace.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: invalid regex_replace pattern %q: %v", col.Name, col.RegexReplace.Pattern, err)
+			}
+			p.steps = append(p.steps, regexReplaceStep{column: targetName, pattern: re, replacement: col.RegexReplace.Replacement})
+		}
+		if col.Hash != "" {
+			if !strings.EqualFold(col.Hash, "sha256") {
+				return nil, fmt.Errorf("column %q: unsupported hash algorithm %q (only sha256 is supported)", col.Name, col.Hash)
+			}
+			p.steps = append(p.steps, hashStep{column: targetName})
+		}
+		if col.Lookup != "" {
+			table, ok := cfg.LookupTables[col.Lookup]
+			if !ok {
+				return nil, fmt.Errorf("column %q: lookup table %q is not defined", col.Name, col.Lookup)
+			}
+			p.steps = append(p.steps, lookupStep{column: targetName, table: table})
+		}
+		if col.Derive != "" {
+			expr, err := govaluate.NewEvaluableExpression(col.Derive)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: invalid derive expression %q: %v", col.Name, col.Derive, err)
+			}
+			p.steps = append(p.steps, deriveStep{column: targetName, expr: expr})
+		}
+		if col.DropIf != "" {
+			expr, err := govaluate.NewEvaluableExpression(col.DropIf)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: invalid drop_if expression %q: %v", col.Name, col.DropIf, err)
+			}
+			p.steps = append(p.steps, dropIfStep{expr: expr})
+		}
+	}
+	return p, nil
+}
+
+// renameStep renames column from to to, leaving its value unchanged.
+type renameStep struct {
+	from, to string
+}
+
+func (s renameStep) Apply(record Record) (bool, error) {
+	if v, ok := record[s.from]; ok {
+		delete(record, s.from)
+		record[s.to] = v
+	}
+	return true, nil
+}
+
+// castStep coerces column's value to one of "string", "int", "float", or
+// "bool", using the same loose string<->numeric coercion LoadToPostgreSQL
+// already relies on between file-source string values and Postgres target
+// types.
+type castStep struct {
+	column string
+	to     string
+}
+
+func (s castStep) Apply(record Record) (bool, error) {
+	v, ok := record[s.column]
+	if !ok || v == nil {
+		return true, nil
+	}
+
+	switch strings.ToLower(s.to) {
+	case "string":
+		record[s.column] = fmt.Sprintf("%v", v)
+	case "int":
+		n, err := toInt64(v)
+		if err != nil {
+			return false, fmt.Errorf("cast column %q to int: %v", s.column, err)
+		}
+		record[s.column] = n
+	case "float":
+		f, err := toFloat64(v)
+		if err != nil {
+			return false, fmt.Errorf("cast column %q to float: %v", s.column, err)
+		}
+		record[s.column] = f
+	case "bool":
+		b, err := toBoolValue(v)
+		if err != nil {
+			return false, fmt.Errorf("cast column %q to bool: %v", s.column, err)
+		}
+		record[s.column] = b
+	default:
+		return false, fmt.Errorf("cast column %q: unsupported target type %q", s.column, s.to)
+	}
+	return true, nil
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case float64:
+		return int64(n), nil
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+	default:
+		return 0, fmt.Errorf("cannot coerce %T to int", v)
+	}
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int64:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(n), 64)
+	default:
+		return 0, fmt.Errorf("cannot coerce %T to float", v)
+	}
+}
+
+func toBoolValue(v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(b))
+	default:
+		return false, fmt.Errorf("cannot coerce %T to bool", v)
+	}
+}
+
+// trimStep trims leading/trailing whitespace from column's string value.
+type trimStep struct {
+	column string
+}
+
+func (s trimStep) Apply(record Record) (bool, error) {
+	if v, ok := record[s.column].(string); ok {
+		record[s.column] = strings.TrimSpace(v)
+	}
+	return true, nil
+}
+
+// regexReplaceStep replaces every match of pattern in column's string
+// value with replacement.
+type regexReplaceStep struct {
+	column      string
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+func (s regexReplaceStep) Apply(record Record) (bool, error) {
+	if v, ok := record[s.column].(string); ok {
+		record[s.column] = s.pattern.ReplaceAllString(v, s.replacement)
+	}
+	return true, nil
+}
+
+// hashStep replaces column's value with its hex-encoded SHA-256 digest,
+// for redacting sensitive columns (emails, PII) before they reach the lake
+// or warehouse.
+type hashStep struct {
+	column string
+}
+
+func (s hashStep) Apply(record Record) (bool, error) {
+	v, ok := record[s.column]
+	if !ok || v == nil {
+		return true, nil
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v", v)))
+	record[s.column] = hex.EncodeToString(sum[:])
+	return true, nil
+}
+
+// lookupStep replaces column's value with table[value], leaving the value
+// unchanged if it has no entry in table.
+type lookupStep struct {
+	column string
+	table  map[string]string
+}
+
+func (s lookupStep) Apply(record Record) (bool, error) {
+	key := fmt.Sprintf("%v", record[s.column])
+	if mapped, ok := s.table[key]; ok {
+		record[s.column] = mapped
+	}
+	return true, nil
+}
+
+// deriveStep sets column to the result of evaluating expr against the
+// record's current values.
+type deriveStep struct {
+	column string
+	expr   *govaluate.EvaluableExpression
+}
+
+func (s deriveStep) Apply(record Record) (bool, error) {
+	result, err := s.expr.Evaluate(record)
+	if err != nil {
+		return false, fmt.Errorf("evaluate derive expression for column %q: %v", s.column, err)
+	}
+	record[s.column] = result
+	return true, nil
+}
+
+// dropIfStep discards the whole record when expr evaluates to true; a
+// non-boolean result is an error.
+type dropIfStep struct {
+	expr *govaluate.EvaluableExpression
+}
+
+func (s dropIfStep) Apply(record Record) (bool, error) {
+	result, err := s.expr.Evaluate(record)
+	if err != nil {
+		return false, fmt.Errorf("evaluate drop_if expression: %v", err)
+	}
+	keep, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("drop_if expression must evaluate to a boolean, got %T", result)
+	}
+	return !keep, nil
+}
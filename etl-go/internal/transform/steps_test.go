@@ -0,0 +1,235 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/Knetic/govaluate"
+)
+
+func TestToInt64(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      interface{}
+		want    int64
+		wantErr bool
+	}{
+		{name: "int64", in: int64(42), want: 42},
+		{name: "int", in: 7, want: 7},
+		{name: "float64 truncates", in: 3.9, want: 3},
+		{name: "string", in: "123", want: 123},
+		{name: "string with surrounding whitespace", in: "  42 ", want: 42},
+		{name: "unparseable string", in: "not-a-number", wantErr: true},
+		{name: "unsupported type", in: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toInt64(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("toInt64(%v) = %v, nil; want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toInt64(%v) returned unexpected error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("toInt64(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToFloat64(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      interface{}
+		want    float64
+		wantErr bool
+	}{
+		{name: "float64", in: 3.5, want: 3.5},
+		{name: "int64", in: int64(4), want: 4},
+		{name: "int", in: 4, want: 4},
+		{name: "string", in: "2.75", want: 2.75},
+		{name: "string with surrounding whitespace", in: " 2.75 ", want: 2.75},
+		{name: "unparseable string", in: "nope", wantErr: true},
+		{name: "unsupported type", in: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toFloat64(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("toFloat64(%v) = %v, nil; want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toFloat64(%v) returned unexpected error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("toFloat64(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToBoolValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      interface{}
+		want    bool
+		wantErr bool
+	}{
+		{name: "bool true", in: true, want: true},
+		{name: "bool false", in: false, want: false},
+		{name: "string true", in: "true", want: true},
+		{name: "string 1", in: "1", want: true},
+		{name: "string with surrounding whitespace", in: " false ", want: false},
+		{name: "unparseable string", in: "maybe", wantErr: true},
+		{name: "unsupported type", in: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toBoolValue(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("toBoolValue(%v) = %v, nil; want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toBoolValue(%v) returned unexpected error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("toBoolValue(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCastStepApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		to      string
+		in      interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "to string", to: "string", in: 42, want: "42"},
+		{name: "to int from string", to: "int", in: "99", want: int64(99)},
+		{name: "to float from int", to: "float", in: 5, want: float64(5)},
+		{name: "to bool from string", to: "bool", in: "true", want: true},
+		{name: "case-insensitive target", to: "INT", in: "3", want: int64(3)},
+		{name: "bad int coercion", to: "int", in: "nope", wantErr: true},
+		{name: "unsupported target type", to: "date", in: "2020-01-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := Record{"v": tt.in}
+			step := castStep{column: "v", to: tt.to}
+			keep, err := step.Apply(record)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Apply() = keep %v, err nil; want error", keep)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() returned unexpected error: %v", err)
+			}
+			if !keep {
+				t.Fatalf("Apply() returned keep=false; want true")
+			}
+			if record["v"] != tt.want {
+				t.Errorf("record[%q] = %v (%T), want %v (%T)", "v", record["v"], record["v"], tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestCastStepApplyMissingOrNilColumnIsNoop(t *testing.T) {
+	step := castStep{column: "v", to: "int"}
+
+	record := Record{}
+	if keep, err := step.Apply(record); err != nil || !keep {
+		t.Fatalf("Apply() on missing column = keep %v, err %v; want true, nil", keep, err)
+	}
+
+	record = Record{"v": nil}
+	if keep, err := step.Apply(record); err != nil || !keep {
+		t.Fatalf("Apply() on nil column = keep %v, err %v; want true, nil", keep, err)
+	}
+	if record["v"] != nil {
+		t.Errorf("record[%q] = %v, want nil untouched", "v", record["v"])
+	}
+}
+
+func TestDeriveStepApply(t *testing.T) {
+	expr, err := govaluate.NewEvaluableExpression("price * qty")
+	if err != nil {
+		t.Fatalf("NewEvaluableExpression: %v", err)
+	}
+	step := deriveStep{column: "total", expr: expr}
+
+	record := Record{"price": 2.5, "qty": 4.0}
+	keep, err := step.Apply(record)
+	if err != nil {
+		t.Fatalf("Apply() returned unexpected error: %v", err)
+	}
+	if !keep {
+		t.Fatalf("Apply() returned keep=false; want true")
+	}
+	if record["total"] != 10.0 {
+		t.Errorf("record[%q] = %v, want 10.0", "total", record["total"])
+	}
+}
+
+func TestDeriveStepApplyUndefinedVariableErrors(t *testing.T) {
+	expr, err := govaluate.NewEvaluableExpression("qty")
+	if err != nil {
+		t.Fatalf("NewEvaluableExpression: %v", err)
+	}
+	step := deriveStep{column: "total", expr: expr}
+
+	if _, err := step.Apply(Record{}); err == nil {
+		t.Fatal("Apply() with an undefined variable = nil error; want error")
+	}
+}
+
+func TestDropIfStepApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		record   Record
+		wantKeep bool
+		wantErr  bool
+	}{
+		{name: "truthy drops record", expr: "status == 'deleted'", record: Record{"status": "deleted"}, wantKeep: false},
+		{name: "falsy keeps record", expr: "status == 'deleted'", record: Record{"status": "active"}, wantKeep: true},
+		{name: "non-bool result errors", expr: "amount", record: Record{"amount": 5.0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := govaluate.NewEvaluableExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("NewEvaluableExpression: %v", err)
+			}
+			step := dropIfStep{expr: expr}
+
+			keep, err := step.Apply(tt.record)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Apply() = keep %v, err nil; want error", keep)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() returned unexpected error: %v", err)
+			}
+			if keep != tt.wantKeep {
+				t.Errorf("Apply() keep = %v, want %v", keep, tt.wantKeep)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Package transform compiles a declarative column-transform config (HCL if
+// the file's extension is .hcl, YAML otherwise) into an ordered Pipeline of
+// typed Steps, so the rules ETLService.Transform applies to every
+// DataRecord can be edited without a Go change and a rebuild. See hcl.go and
+// yaml.go for the on-disk schemas and steps.go for each operation's Step.
+package transform
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Record is the map a Pipeline transforms in place. It has the same
+// underlying type as the main package's DataRecord; this package stays
+// free of a dependency on main by working on the underlying map directly.
+type Record = map[string]interface{}
+
+// Pipeline is a compiled, ordered sequence of Steps.
+type Pipeline struct {
+	steps []Step
+}
+
+// Apply runs every step against record in order, returning false if a
+// drop_if step matched and the record should be discarded.
+func (p *Pipeline) Apply(record Record) (bool, error) {
+	for _, step := range p.steps {
+		keep, err := step.Apply(record)
+		if err != nil {
+			return false, err
+		}
+		if !keep {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Load reads path and compiles it into a Pipeline: an .hcl extension
+// selects the HCL loader, anything else the YAML loader.
+func Load(path string) (*Pipeline, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(cfg)
+}
+
+func loadConfig(path string) (*config, error) {
+	if strings.EqualFold(filepath.Ext(path), ".hcl") {
+		return loadHCLConfig(path)
+	}
+	return loadYAMLConfig(path)
+}
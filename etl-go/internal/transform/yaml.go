@@ -0,0 +1,74 @@
+package transform
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// yamlConfig is the YAML on-disk schema:
+//
+//	lookup_tables:
+//	  country_names:
+//	    US: United States
+//	    CA: Canada
+//	columns:
+//	  - name: user_email
+//	    hash: sha256
+//	  - name: country
+//	    lookup: country_names
+//	  - name: status
+//	    drop_if: "status == \"deleted\""
+type yamlConfig struct {
+	LookupTables map[string]map[string]string `yaml:"lookup_tables"`
+	Columns      []yamlColumnConfig            `yaml:"columns"`
+}
+
+type yamlColumnConfig struct {
+	Name         string             `yaml:"name"`
+	Rename       string             `yaml:"rename"`
+	Cast         string             `yaml:"cast"`
+	Trim         bool               `yaml:"trim"`
+	RegexReplace *yamlRegexReplace  `yaml:"regex_replace"`
+	Hash         string             `yaml:"hash"`
+	Lookup       string             `yaml:"lookup"`
+	Derive       string             `yaml:"derive"`
+	DropIf       string             `yaml:"drop_if"`
+}
+
+type yamlRegexReplace struct {
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+func loadYAMLConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transform config %s: %v", path, err)
+	}
+
+	var parsed yamlConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse transform config %s: %v", path, err)
+	}
+
+	cfg := &config{LookupTables: parsed.LookupTables}
+	for _, col := range parsed.Columns {
+		c := columnConfig{
+			Name:   col.Name,
+			Rename: col.Rename,
+			Cast:   col.Cast,
+			Trim:   col.Trim,
+			Hash:   col.Hash,
+			Lookup: col.Lookup,
+			Derive: col.Derive,
+			DropIf: col.DropIf,
+		}
+		if col.RegexReplace != nil {
+			c.RegexReplace = &regexReplaceConfig{Pattern: col.RegexReplace.Pattern, Replacement: col.RegexReplace.Replacement}
+		}
+		cfg.Columns = append(cfg.Columns, c)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Sink is a destination ETLService can load a transformed batch into. It
+// exists so the pipeline isn't hard-wired to one MinIO blob plus one
+// Postgres table: callers register whichever Sinks they need via
+// RegisterSink and select them at runtime with ETL_SINKS, instead of the
+// pipeline calling LoadToLake/LoadToPostgreSQL directly.
+//
+// Write stages data for the sink; Commit finalizes whatever Write staged
+// (a no-op for sinks, like minioSink and postgresSink, that write each
+// record immediately). Sinks that buffer across multiple Write calls
+// before producing a durable artifact, like IcebergSink, do the real work
+// in Commit.
+type Sink interface {
+	Write(ctx context.Context, schema []string, batch []DataRecord) error
+	Commit(ctx context.Context) error
+}
+
+// RegisterSink adds s under name to e's sink registry, so it can be
+// selected via ETL_SINKS. Registering under a name that's already in use
+// replaces the existing sink.
+func (e *ETLService) RegisterSink(name string, s Sink) {
+	if e.sinks == nil {
+		e.sinks = make(map[string]Sink)
+	}
+	e.sinks[name] = s
+}
+
+// LoadToSinks writes data to every sink named in names (as registered via
+// RegisterSink), in order, committing each after its Write call. It stops
+// and returns the first error encountered, leaving later sinks untouched.
+func (e *ETLService) LoadToSinks(ctx context.Context, names []string, data []DataRecord, fileName string) error {
+	columns := buildFlatColumns(data)
+	for _, name := range names {
+		sink, ok := e.sinks[name]
+		if !ok {
+			return fmt.Errorf("unknown sink %q (registered: %v)", name, e.sinkNames())
+		}
+		if err := sink.Write(ctx, columns, data); err != nil {
+			return fmt.Errorf("sink %q: write failed: %v", name, err)
+		}
+		if err := sink.Commit(ctx); err != nil {
+			return fmt.Errorf("sink %q: commit failed: %v", name, err)
+		}
+	}
+	_ = fileName
+	return nil
+}
+
+// sinkNames returns e's registered sink names, for error messages.
+func (e *ETLService) sinkNames() []string {
+	names := make([]string, 0, len(e.sinks))
+	for name := range e.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// minioSink adapts LoadToLake to the Sink interface: each Write uploads
+// data to the object store immediately, so Commit is a no-op.
+type minioSink struct {
+	etl      *ETLService
+	fileName string
+}
+
+func (s *minioSink) Write(ctx context.Context, schema []string, batch []DataRecord) error {
+	return s.etl.LoadToLake(batch, s.fileName)
+}
+
+func (s *minioSink) Commit(ctx context.Context) error { return nil }
+
+// postgresSink adapts LoadToPostgreSQL to the Sink interface: each Write
+// COPYs data into Postgres immediately, so Commit is a no-op.
+type postgresSink struct {
+	etl *ETLService
+}
+
+func (s *postgresSink) Write(ctx context.Context, schema []string, batch []DataRecord) error {
+	return s.etl.LoadToPostgreSQL(batch)
+}
+
+func (s *postgresSink) Commit(ctx context.Context) error { return nil }
+
+// NewMinIOSink returns a Sink that loads each batch to the object store lake
+// under fileName via LoadToLake, in the format selected by LAKE_OUTPUT_FORMAT.
+func NewMinIOSink(etl *ETLService, fileName string) Sink {
+	return &minioSink{etl: etl, fileName: fileName}
+}
+
+// NewPostgresSink returns a Sink that loads each batch into PostgreSQL via
+// LoadToPostgreSQL.
+func NewPostgresSink(etl *ETLService) Sink {
+	return &postgresSink{etl: etl}
+}
+
+// icebergDataFile describes one Parquet data file an IcebergSink commit
+// wrote, as recorded in the snapshot's manifest.
+type icebergDataFile struct {
+	Path     string `json:"path"`
+	RowCount int    `json:"row_count"`
+}
+
+// icebergManifest is the v2-Iceberg-spec subset IcebergSink writes per
+// snapshot: the list of data files a query engine must read to reconstruct
+// the table as of that snapshot.
+type icebergManifest struct {
+	SnapshotID int64             `json:"snapshot-id"`
+	DataFiles  []icebergDataFile `json:"data-files"`
+}
+
+// icebergSnapshot is the snapshot metadata document IcebergSink writes
+// alongside each manifest, mirroring the fields a real Iceberg
+// TableMetadata's "snapshots" entry carries.
+type icebergSnapshot struct {
+	SnapshotID   int64    `json:"snapshot-id"`
+	ParentID     *int64   `json:"parent-snapshot-id"`
+	TimestampMs  int64    `json:"timestamp-ms"`
+	ManifestList string   `json:"manifest-list"`
+	Schema       []string `json:"schema"`
+	Summary      string   `json:"summary"`
+}
+
+// IcebergSink implements Sink on top of an ObjectStore, writing Parquet
+// data files plus a manifest and snapshot metadata document (a v2 Iceberg
+// spec subset) under a metadata/ prefix, so downstream engines get
+// time-travel reads without a table format library. Each Commit produces
+// one snapshot: a new snap-<uuid>.avro.json manifest, a snap-<uuid>.json
+// snapshot document, and an updated version-hint.text pointing at the
+// snapshot document. Commits from concurrent writers are not coordinated:
+// each reads version-hint.text and then overwrites it, so the last write
+// wins. Write stages rows in memory; nothing is durable until Commit runs.
+type IcebergSink struct {
+	etl     *ETLService
+	dataset string
+	pending []DataRecord
+}
+
+// NewIcebergSink returns an IcebergSink that writes dataset's data files and
+// metadata under raw/dataset=<dataset>/ in etl's object store.
+func NewIcebergSink(etl *ETLService, dataset string) *IcebergSink {
+	return &IcebergSink{etl: etl, dataset: SanitizeTableName(dataset)}
+}
+
+// Write stages batch for the next Commit; IcebergSink buffers rows rather
+// than writing them immediately so a single snapshot can span several
+// Write calls (e.g. one per extracted chunk).
+func (s *IcebergSink) Write(ctx context.Context, schema []string, batch []DataRecord) error {
+	s.pending = append(s.pending, batch...)
+	return nil
+}
+
+// Commit writes s.pending as a new Parquet data file, then atomically
+// publishes it as a new snapshot: a manifest listing the file, a
+// snap-<uuid>.json snapshot document pointing at that manifest, and an
+// updated version-hint.text naming the new snapshot as current. Pending
+// rows are cleared whether or not Commit succeeds, so a failed commit
+// doesn't silently get re-attempted with stale + new rows mixed together.
+func (s *IcebergSink) Commit(ctx context.Context) error {
+	rows := s.pending
+	s.pending = nil
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns := buildFlatColumns(rows)
+	schemaJSON, colTypes, err := inferredParquetSchema(rows, columns)
+	if err != nil {
+		return fmt.Errorf("failed to build iceberg data file schema: %v", err)
+	}
+
+	dataPath := fmt.Sprintf("raw/dataset=%s/data/part-%s.parquet", s.dataset, uuid.NewString())
+	tmpPath := filepath.Join(os.TempDir(), fmt.Sprintf("iceberg-%s.parquet", uuid.NewString()))
+	fw, err := local.NewLocalFileWriter(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to open iceberg data file: %v", err)
+	}
+	pw, err := writer.NewJSONWriter(schemaJSON, fw, 4)
+	if err != nil {
+		fw.Close()
+		return fmt.Errorf("failed to create iceberg parquet writer: %v", err)
+	}
+	for _, record := range rows {
+		rowJSON, err := parquetRowJSON(record, columns, colTypes)
+		if err != nil {
+			pw.WriteStop()
+			fw.Close()
+			return fmt.Errorf("failed to marshal iceberg row: %v", err)
+		}
+		if err := pw.Write(rowJSON); err != nil {
+			pw.WriteStop()
+			fw.Close()
+			return fmt.Errorf("failed to write iceberg row: %v", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		fw.Close()
+		return fmt.Errorf("failed to finalize iceberg data file: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		return fmt.Errorf("failed to close iceberg data file: %v", err)
+	}
+	if err := s.etl.uploadAndRemove(tmpPath, dataPath, "application/vnd.apache.parquet"); err != nil {
+		return fmt.Errorf("failed to upload iceberg data file: %v", err)
+	}
+
+	snapshotID := time.Now().UnixNano()
+	manifest := icebergManifest{
+		SnapshotID: snapshotID,
+		DataFiles:  []icebergDataFile{{Path: dataPath, RowCount: len(rows)}},
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to build iceberg manifest: %v", err)
+	}
+	manifestPath := fmt.Sprintf("raw/dataset=%s/metadata/snap-%s.avro.json", s.dataset, uuid.NewString())
+	if err := s.etl.store.Put(ctx, manifestPath, bytes.NewReader(manifestJSON), int64(len(manifestJSON)), "application/json"); err != nil {
+		return fmt.Errorf("failed to upload iceberg manifest: %v", err)
+	}
+
+	parentID, err := s.currentSnapshotID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read current iceberg snapshot: %v", err)
+	}
+	snapshot := icebergSnapshot{
+		SnapshotID:   snapshotID,
+		ParentID:     parentID,
+		TimestampMs:  time.Now().UnixMilli(),
+		ManifestList: manifestPath,
+		Schema:       columns,
+		Summary:      fmt.Sprintf("added %d records, 1 data file", len(rows)),
+	}
+	snapshotJSON, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to build iceberg snapshot: %v", err)
+	}
+	snapshotPath := fmt.Sprintf("raw/dataset=%s/metadata/snap-%s.json", s.dataset, uuid.NewString())
+	if err := s.etl.store.Put(ctx, snapshotPath, bytes.NewReader(snapshotJSON), int64(len(snapshotJSON)), "application/json"); err != nil {
+		return fmt.Errorf("failed to upload iceberg snapshot: %v", err)
+	}
+
+	versionHintPath := fmt.Sprintf("raw/dataset=%s/metadata/version-hint.text", s.dataset)
+	hint := []byte(snapshotPath)
+	if err := s.etl.store.Put(ctx, versionHintPath, bytes.NewReader(hint), int64(len(hint)), "text/plain"); err != nil {
+		return fmt.Errorf("failed to update iceberg version-hint.text: %v", err)
+	}
+
+	log.Printf("Iceberg sink committed snapshot %d for dataset %s (%d records)", snapshotID, s.dataset, len(rows))
+	return nil
+}
+
+// currentSnapshotID returns the snapshot-id recorded in the dataset's
+// current version-hint.text, or nil if the dataset has no prior snapshot
+// (this is the first commit).
+func (s *IcebergSink) currentSnapshotID(ctx context.Context) (*int64, error) {
+	versionHintPath := fmt.Sprintf("raw/dataset=%s/metadata/version-hint.text", s.dataset)
+	r, err := s.etl.store.Get(ctx, versionHintPath)
+	if err != nil {
+		return nil, nil
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	snapshotPath := buf.String()
+
+	snapR, err := s.etl.store.Get(ctx, snapshotPath)
+	if err != nil {
+		return nil, nil
+	}
+	defer snapR.Close()
+
+	var snapshot icebergSnapshot
+	if err := json.NewDecoder(snapR).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot.SnapshotID, nil
+}
@@ -0,0 +1,533 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// copyHeaderRe matches a pg_dump "COPY schema.table (col1, col2, ...) FROM
+// stdin;" header line.
+var copyHeaderRe = regexp.MustCompile(`(?i)^COPY\s+((?:"[^"]+"|[\w]+)(?:\.(?:"[^"]+"|[\w]+))*)\s*\(([^)]*)\)\s+FROM\s+stdin`)
+
+// insertHeaderRe matches a complete, semicolon-terminated "INSERT INTO
+// schema.table (col1, col2, ...) VALUES (...), (...);" statement, capturing
+// the table, column list, and the raw tuple list to hand to
+// tokenizeValueTuples.
+var insertHeaderRe = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+((?:"[^"]+"|[\w]+)(?:\.(?:"[^"]+"|[\w]+))*)\s*\(([^)]*)\)\s*VALUES\s*(.*);\s*$`)
+
+// ExtractTablesFromFile parses a SQL dump and groups its rows by table name.
+// It transparently handles plain .sql text, gzip-compressed dumps (detected
+// by magic bytes, not just a .gz extension), and tar/tar.gz archives
+// containing one or more .sql members.
+func (e *ETLService) ExtractTablesFromFile(filePath string) (map[string][]DataRecord, error) {
+	tables := make(map[string][]DataRecord)
+	flush := func(table string, records []DataRecord) error {
+		tables[table] = append(tables[table], records...)
+		return nil
+	}
+	if err := e.streamTablesFromFile(filePath, 0, flush); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// ExtractTablesFromFileStreaming is the bounded-memory counterpart to
+// ExtractTablesFromFile: instead of buffering every row, it calls flush with
+// up to batchSize rows at a time per table as the dump is parsed, so a
+// caller can load each batch to the lake (or Postgres) immediately and
+// release it rather than holding the whole dump in memory. batchSize <= 0
+// buffers each table fully and flushes it once, at EOF.
+func (e *ETLService) ExtractTablesFromFileStreaming(filePath string, batchSize int, flush func(table string, records []DataRecord) error) error {
+	return e.streamTablesFromFile(filePath, batchSize, flush)
+}
+
+// ExtractFromDump extracts data from a SQL dump file (plain, gzip-compressed,
+// or a tar/tar.gz archive of dumps), flattening every table's rows into a
+// single slice so it still satisfies the ExtractFromFile contract. Callers
+// that care about per-table grouping or bounded memory usage should use
+// ExtractTablesFromFile/ExtractTablesFromFileStreaming directly instead.
+func (e *ETLService) ExtractFromDump(filePath string) ([]DataRecord, error) {
+	tables, err := e.ExtractTablesFromFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []DataRecord
+	for _, records := range tables {
+		data = append(data, records...)
+	}
+	return data, nil
+}
+
+// ProcessETLFromDumpStreaming runs the bounded-memory variant of the file ETL
+// pipeline for large SQL dumps: each table's rows are transformed and pushed
+// to the lake in batches of batchSize as they're parsed, instead of loading
+// the entire dump into memory first. Postgres loading is intentionally left
+// to the regular (buffered) pipeline, since LoadToPostgreSQL derives its
+// table name from the full batch rather than the dump's table names.
+func (e *ETLService) ProcessETLFromDumpStreaming(filePath string, batchSize int) error {
+	flush := func(table string, records []DataRecord) error {
+		transformed := e.Transform(records)
+		fileName := fmt.Sprintf("raw_%s_%s", table, filepath.Base(filePath))
+		if err := e.LoadToLake(transformed, fileName); err != nil {
+			return fmt.Errorf("load to lake failed for table %s: %v", table, err)
+		}
+		log.Printf("Flushed %d rows for table %s from %s", len(records), table, filePath)
+		return nil
+	}
+
+	if err := e.ExtractTablesFromFileStreaming(filePath, batchSize, flush); err != nil {
+		return fmt.Errorf("streaming extract failed: %v", err)
+	}
+
+	log.Println("Streaming ETL process from dump completed successfully")
+	return nil
+}
+
+// streamTablesFromFile opens filePath, transparently unwraps gzip and
+// tar/tar.gz framing, and parses the resulting SQL stream(s), invoking flush
+// per table per batch.
+func (e *ETLService) streamTablesFromFile(filePath string, batchSize int, flush func(table string, records []DataRecord) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	peek, peekErr := reader.Peek(2)
+	isGzip := peekErr == nil && len(peek) == 2 && peek[0] == 0x1f && peek[1] == 0x8b
+
+	var sqlReader io.Reader = reader
+	if isGzip {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip dump %s: %v", filePath, err)
+		}
+		defer gz.Close()
+		sqlReader = gz
+	}
+
+	if isTarArchive(filePath, isGzip) {
+		return streamTablesFromTar(sqlReader, batchSize, flush)
+	}
+
+	return extractTablesFromSQLStream(sqlReader, batchSize, flush)
+}
+
+// isTarArchive reports whether filePath names a tar or tar.gz archive. pg_dump
+// archives don't carry a magic byte that distinguishes tar from plain SQL
+// text until deep into the first block's ustar header, so the file extension
+// is the practical signal here, same as GetFileFormat already uses.
+func isTarArchive(filePath string, isGzip bool) bool {
+	lower := strings.ToLower(filePath)
+	if strings.HasSuffix(lower, ".tar") {
+		return true
+	}
+	return isGzip && strings.HasSuffix(lower, ".tar.gz")
+}
+
+// streamTablesFromTar walks a tar archive's members, parsing every .sql file
+// found and merging their tables via flush.
+func streamTablesFromTar(r io.Reader, batchSize int, flush func(table string, records []DataRecord) error) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(strings.ToLower(header.Name), ".sql") {
+			continue
+		}
+		if err := extractTablesFromSQLStream(tr, batchSize, flush); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", header.Name, err)
+		}
+	}
+}
+
+// extractTablesFromSQLStream reads a pg_dump-style SQL stream, recognizing
+// "COPY ... FROM stdin;" blocks and "INSERT INTO ... VALUES (...), (...);"
+// statements (which may span multiple lines), grouping parsed rows by table
+// and invoking flush once a table accumulates batchSize rows (batchSize <= 0
+// flushes each table exactly once, at EOF).
+func extractTablesFromSQLStream(dumpReader io.Reader, batchSize int, flush func(table string, records []DataRecord) error) error {
+	reader := bufio.NewReaderSize(dumpReader, 64*1024)
+	batches := make(map[string][]DataRecord)
+
+	flushTable := func(table string) error {
+		records := batches[table]
+		if len(records) == 0 {
+			return nil
+		}
+		delete(batches, table)
+		return flush(table, records)
+	}
+
+	appendRecord := func(table string, record DataRecord) error {
+		batches[table] = append(batches[table], record)
+		if batchSize > 0 && len(batches[table]) >= batchSize {
+			return flushTable(table)
+		}
+		return nil
+	}
+
+	var inCopy bool
+	var copyTable string
+	var copyColumns []string
+	var stmt strings.Builder
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line == "" && readErr != nil {
+			break
+		}
+
+		if inCopy {
+			trimmed := strings.TrimRight(line, "\r\n")
+			if trimmed == `\.` {
+				inCopy = false
+				copyTable = ""
+				copyColumns = nil
+			} else if record, ok := parseCopyRow(trimmed, copyColumns); ok {
+				if err := appendRecord(copyTable, record); err != nil {
+					return err
+				}
+			}
+			if readErr != nil {
+				break
+			}
+			continue
+		}
+
+		if stmt.Len() == 0 {
+			if table, columns, ok := parseCopyHeader(line); ok {
+				inCopy = true
+				copyTable = table
+				copyColumns = columns
+				if readErr != nil {
+					break
+				}
+				continue
+			}
+		}
+
+		stmt.WriteString(line)
+		if statementComplete(stmt.String()) {
+			full := stmt.String()
+			stmt.Reset()
+
+			if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(full)), "INSERT INTO") {
+				table, columns, rows, err := parseInsertStatement(full)
+				if err == nil {
+					for _, values := range rows {
+						record := make(DataRecord, len(columns))
+						for i, col := range columns {
+							if i < len(values) {
+								record[col] = values[i]
+							}
+						}
+						if err := appendRecord(table, record); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	for table := range batches {
+		if err := flushTable(table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// statementComplete reports whether s, taken as accumulated-so-far SQL text,
+// ends (ignoring trailing whitespace) with an unquoted ";" — i.e. whether the
+// statement is ready to be parsed, accounting for "''"-escaped and backslash-
+// escaped quotes so a semicolon inside a string literal doesn't end it early.
+func statementComplete(s string) bool {
+	trimmed := strings.TrimRight(s, " \t\r\n")
+	inQuote := false
+	for i := 0; i < len(trimmed); i++ {
+		c := trimmed[i]
+		if !inQuote {
+			if c == '\'' {
+				inQuote = true
+			}
+			continue
+		}
+		switch c {
+		case '\\':
+			i++ // skip whatever follows a backslash escape inside the string
+		case '\'':
+			if i+1 < len(trimmed) && trimmed[i+1] == '\'' {
+				i++ // escaped '' stays inside the string
+			} else {
+				inQuote = false
+			}
+		}
+	}
+	return !inQuote && strings.HasSuffix(trimmed, ";")
+}
+
+// parseCopyHeader matches a COPY ... FROM stdin; header line, returning the
+// table name and column list.
+func parseCopyHeader(line string) (table string, columns []string, ok bool) {
+	m := copyHeaderRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return "", nil, false
+	}
+	table = unquoteIdentifier(m[1])
+	for _, col := range strings.Split(m[2], ",") {
+		columns = append(columns, unquoteIdentifier(strings.TrimSpace(col)))
+	}
+	return table, columns, true
+}
+
+// parseCopyRow parses one tab-separated COPY stdin row into a DataRecord
+// keyed by columns, unescaping pg_dump's backslash escapes and treating the
+// literal "\N" field as NULL.
+func parseCopyRow(line string, columns []string) (DataRecord, bool) {
+	if len(columns) == 0 {
+		return nil, false
+	}
+	fields := strings.Split(line, "\t")
+	record := make(DataRecord, len(columns))
+	for i, col := range columns {
+		if i >= len(fields) {
+			record[col] = nil
+			continue
+		}
+		record[col] = unescapeCopyField(fields[i])
+	}
+	return record, true
+}
+
+// unescapeCopyField decodes a single COPY stdin field: "\N" is NULL, and
+// "\t", "\n", "\r", and "\\" are unescaped to their literal characters.
+func unescapeCopyField(field string) interface{} {
+	if field == `\N` {
+		return nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c == '\\' && i+1 < len(field) {
+			i++
+			switch field[i] {
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\':
+				b.WriteByte('\\')
+			default:
+				b.WriteByte(field[i])
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// parseInsertStatement parses a complete "INSERT INTO table (cols) VALUES
+// (...), (...);" statement into its table name, column list, and decoded
+// value tuples.
+func parseInsertStatement(stmt string) (table string, columns []string, rows [][]interface{}, err error) {
+	m := insertHeaderRe.FindStringSubmatch(stmt)
+	if m == nil {
+		return "", nil, nil, fmt.Errorf("statement does not match INSERT INTO ... VALUES ... (...);")
+	}
+
+	table = unquoteIdentifier(m[1])
+	for _, col := range strings.Split(m[2], ",") {
+		columns = append(columns, unquoteIdentifier(strings.TrimSpace(col)))
+	}
+
+	rows, err = tokenizeValueTuples(m[3])
+	return table, columns, rows, err
+}
+
+// tokenizeValueTuples tokenizes a "(...), (...), ..." value-tuple list (the
+// part of an INSERT statement after VALUES, with the trailing ";" already
+// stripped) into one []interface{} per row.
+func tokenizeValueTuples(s string) ([][]interface{}, error) {
+	var rows [][]interface{}
+	i, n := 0, len(s)
+
+	skipSpace := func() {
+		for i < n && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+			i++
+		}
+	}
+
+	for {
+		skipSpace()
+		if i >= n {
+			break
+		}
+		if s[i] != '(' {
+			return nil, fmt.Errorf("expected '(' at offset %d", i)
+		}
+		i++
+
+		var values []interface{}
+		for {
+			skipSpace()
+			if i >= n {
+				return nil, fmt.Errorf("unterminated value tuple")
+			}
+			if s[i] == ')' {
+				i++
+				break
+			}
+
+			val, next, err := parseValue(s, i)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, val)
+			i = next
+
+			skipSpace()
+			if i < n && s[i] == ',' {
+				i++
+				continue
+			}
+			skipSpace()
+			if i < n && s[i] == ')' {
+				i++
+				break
+			}
+		}
+		rows = append(rows, values)
+
+		skipSpace()
+		if i < n && s[i] == ',' {
+			i++
+			continue
+		}
+		break
+	}
+
+	return rows, nil
+}
+
+// parseValue parses a single value starting at s[i]: a possibly E-prefixed
+// quoted string, the bare keyword NULL, or any other bare token (numerics,
+// booleans, etc.), returned as a string for the caller to interpret. It
+// returns the offset just past the parsed value.
+func parseValue(s string, i int) (interface{}, int, error) {
+	n := len(s)
+
+	if i+1 < n && (s[i] == 'E' || s[i] == 'e') && s[i+1] == '\'' {
+		return parseQuotedString(s, i+1, true)
+	}
+	if s[i] == '\'' {
+		return parseQuotedString(s, i, false)
+	}
+
+	start := i
+	for i < n && s[i] != ',' && s[i] != ')' {
+		i++
+	}
+	token := strings.TrimSpace(s[start:i])
+	if strings.EqualFold(token, "NULL") {
+		return nil, i, nil
+	}
+	return token, i, nil
+}
+
+// parseQuotedString parses a single-quoted string literal starting at s[i],
+// handling standard ''-escaped quotes and, when eEscape is true (the value
+// was E'...'-prefixed), backslash escapes including \xHH hex bytes. It
+// returns the offset just past the closing quote.
+func parseQuotedString(s string, i int, eEscape bool) (interface{}, int, error) {
+	n := len(s)
+	if i >= n || s[i] != '\'' {
+		return nil, i, fmt.Errorf("expected opening quote at offset %d", i)
+	}
+	i++
+
+	var b strings.Builder
+	for i < n {
+		c := s[i]
+
+		if eEscape && c == '\\' && i+1 < n {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\':
+				b.WriteByte('\\')
+			case '\'':
+				b.WriteByte('\'')
+			case 'x':
+				if i+2 < n {
+					if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+						b.WriteByte(byte(v))
+						i += 3
+						continue
+					}
+				}
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte(s[i])
+			}
+			i++
+			continue
+		}
+
+		if c == '\'' {
+			if i+1 < n && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i += 2
+				continue
+			}
+			i++
+			return b.String(), i, nil
+		}
+
+		b.WriteByte(c)
+		i++
+	}
+
+	return nil, i, fmt.Errorf("unterminated quoted string")
+}
+
+// unquoteIdentifier takes the last dot-separated segment of a possibly
+// schema-qualified, possibly double-quoted identifier (e.g. "public"."users"
+// or public.users) and returns the bare name, which is what rows are grouped
+// by.
+func unquoteIdentifier(ident string) string {
+	parts := strings.Split(ident, ".")
+	last := parts[len(parts)-1]
+	return strings.Trim(last, `"`)
+}